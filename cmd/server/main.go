@@ -5,11 +5,18 @@ import (
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/getsentry/sentry-go"
+	"github.com/superhorsy/quest-app-backend/internal/auth"
+	"github.com/superhorsy/quest-app-backend/internal/auth/oidc"
+	authStore "github.com/superhorsy/quest-app-backend/internal/auth/store"
 	"github.com/superhorsy/quest-app-backend/internal/config"
+	"github.com/superhorsy/quest-app-backend/internal/contacts"
+	contactStore "github.com/superhorsy/quest-app-backend/internal/contacts/store"
 	"github.com/superhorsy/quest-app-backend/internal/core/app"
 	"github.com/superhorsy/quest-app-backend/internal/core/drivers/psql"
 	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+	"github.com/superhorsy/quest-app-backend/internal/core/keyset"
 	"github.com/superhorsy/quest-app-backend/internal/core/listeners/http"
+	"github.com/superhorsy/quest-app-backend/internal/core/listeners/scheduler"
 	"github.com/superhorsy/quest-app-backend/internal/core/logging"
 	"github.com/superhorsy/quest-app-backend/internal/events"
 	"github.com/superhorsy/quest-app-backend/internal/media"
@@ -19,6 +26,7 @@ import (
 	questStore "github.com/superhorsy/quest-app-backend/internal/quests/store"
 	httptransport "github.com/superhorsy/quest-app-backend/internal/transport/http"
 	"github.com/superhorsy/quest-app-backend/internal/users"
+	"github.com/superhorsy/quest-app-backend/internal/users/password"
 	userStore "github.com/superhorsy/quest-app-backend/internal/users/store"
 	"go.uber.org/zap"
 )
@@ -40,6 +48,19 @@ func appStart(ctx context.Context, a *app.App) ([]app.Listener, error) {
 		return nil, err
 	}
 
+	// Load the keys tokens are signed with
+	keys, err := keyset.New(cfg.JWT, cfg.JwtPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	helpers.SetSigningKeys(keys)
+
+	// Load the password policy
+	passwords, err := password.New(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	// Connect to the DB
 	db, err := initDatabase(ctx, cfg, a)
 	if err != nil {
@@ -67,12 +88,24 @@ func appStart(ctx context.Context, a *app.App) ([]app.Listener, error) {
 	mrs := mediaRecordStore.New(db.GetDB())
 	// Storage for static content
 	mfs := localFileStorage.New()
+	as := authStore.New(db.GetDB())
+	cs := contactStore.New(db.GetDB())
 	e := events.New()
-	u := users.New(us, e)
-	q := quests.New(qs, e)
+	u, err := users.New(us, passwords, e)
+	if err != nil {
+		return nil, err
+	}
+	// Promote the admins from the config, the first admin can't be promoted by anyone else
+	if err := u.PromoteAdmins(ctx, cfg.AdminEmails); err != nil {
+		return nil, err
+	}
 	m := media.New(mrs, mfs, e)
+	q := quests.New(qs, m, e)
+	au := auth.New(as, oidc.NewProviders(cfg.OIDC), e)
+
+	c := contacts.New(cs)
 
-	httpServer := httptransport.New(u, q, db.GetDB(), m)
+	httpServer := httptransport.New(u, q, db.GetDB(), m, au, c)
 
 	// Create an HTTP server
 	h, err := http.New(httpServer, cfg.HTTP, ctx)
@@ -80,9 +113,15 @@ func appStart(ctx context.Context, a *app.App) ([]app.Listener, error) {
 		return nil, err
 	}
 
+	// Purge the trash of quests and steps deleted longer than the retention ago
+	purge := scheduler.New("purge_trash", cfg.Trash.PurgeInterval, func(ctx context.Context) error {
+		return q.PurgeTrash(ctx, cfg.Trash.Retention)
+	})
+
 	// Start listening for HTTP requests
 	return []app.Listener{
 		h,
+		purge,
 	}, nil
 }
 
@@ -14,6 +14,7 @@ require (
 	github.com/jmoiron/sqlx v1.3.4
 	github.com/lib/pq v1.10.0
 	github.com/rs/cors v1.8.2
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.30.0
 	go.opentelemetry.io/otel v1.6.0
 	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.6.0
@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/auth/oidc"
+	authStore "github.com/superhorsy/quest-app-backend/internal/auth/store"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"github.com/superhorsy/quest-app-backend/internal/events"
+	"go.uber.org/zap"
+)
+
+const (
+	// ErrInvalidToken is returned when an access token is malformed, expired or revoked.
+	ErrInvalidToken = errors.Error("invalid_token: token is invalid")
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked.
+	ErrInvalidRefreshToken = errors.Error("invalid_refresh_token: refresh token is invalid")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again.
+	ErrRefreshTokenReused = errors.Error("refresh_token_reused: refresh token was already used")
+	// ErrSessionNotFound is returned when the user has no active session with the id.
+	ErrSessionNotFound = errors.Error("session_not_found: session not found")
+	// ErrAccountDisabled is returned when tokens are requested for an account disabled by an admin.
+	ErrAccountDisabled = errors.Error("account_disabled: account is disabled")
+)
+
+// Store represents a type for storing tokens in a database.
+type Store interface {
+	InsertRefreshToken(ctx context.Context, token *model.RefreshToken) (*model.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string, replacedBy *string) error
+	RevokeFamily(ctx context.Context, familyId string) error
+	RevokeUserRefreshTokens(ctx context.Context, userId string) error
+	IsFamilyActive(ctx context.Context, familyId string) (bool, error)
+	InsertPasswordResetToken(ctx context.Context, token *model.PasswordResetToken) (*model.PasswordResetToken, error)
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error)
+	InsertEmailVerificationToken(ctx context.Context, token *model.EmailVerificationToken) (*model.EmailVerificationToken, error)
+	UseEmailVerificationToken(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error)
+	InsertLoginState(ctx context.Context, state *model.LoginState) error
+	TakeLoginState(ctx context.Context, provider string, stateHash string) (*model.LoginState, error)
+	GetIdentity(ctx context.Context, provider string, subject string) (*model.Identity, error)
+	InsertIdentity(ctx context.Context, identity *model.Identity) (*model.Identity, error)
+	GetAccount(ctx context.Context, userId string) (*model.Account, error)
+	InsertLoginAttempt(ctx context.Context, attempt *model.LoginAttempt) error
+	GetAccountLoginFailures(ctx context.Context, email string, since time.Time) (*model.LoginFailures, error)
+	GetMFAFailures(ctx context.Context, userId string, since time.Time) (*model.LoginFailures, error)
+	GetIPLoginFailures(ctx context.Context, ip string, since time.Time) (*model.LoginFailures, error)
+	GetLoginAttempts(ctx context.Context, filter model.LoginAttemptFilter) ([]model.LoginAttempt, error)
+	InsertSession(ctx context.Context, session *model.Session) (*model.Session, error)
+	TouchSession(ctx context.Context, id string, ip *string, interval time.Duration) error
+	GetSession(ctx context.Context, id string) (*model.Session, error)
+	GetActiveSessions(ctx context.Context, userId string) ([]model.Session, error)
+	InsertPersonalAccessToken(ctx context.Context, token *model.PersonalAccessToken) (*model.PersonalAccessToken, error)
+	GetPersonalAccessToken(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error)
+	GetUserPersonalAccessTokens(ctx context.Context, userId string) ([]model.PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, userId string, id string) error
+	RevokeUserPersonalAccessTokens(ctx context.Context, userId string) error
+	TouchPersonalAccessToken(ctx context.Context, id string, interval time.Duration) error
+	UpsertTOTP(ctx context.Context, userId string, secret string) error
+	GetTOTP(ctx context.Context, userId string) (*model.TOTP, error)
+	ConfirmTOTP(ctx context.Context, userId string, step int64, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userId string, step int64) error
+	DeleteTOTP(ctx context.Context, userId string) error
+	ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId string, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userId string) (int, error)
+	InsertMFAChallenge(ctx context.Context, challenge *model.MFAChallenge) error
+	TakeMFAChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (*model.MFAChallenge, error)
+	UseMFAChallenge(ctx context.Context, id string) error
+}
+
+// Events represents a type for producing events on auth operations.
+type Events interface {
+	Produce(ctx context.Context, topic events.Topic, payload interface{})
+}
+
+// Auth provides functionality for issuing, refreshing and revoking tokens.
+type Auth struct {
+	store     Store
+	providers oidc.Providers
+	events    Events
+}
+
+// New will instantiate a new instance of Auth.
+func New(s Store, p oidc.Providers, e Events) *Auth {
+	return &Auth{
+		store:     s,
+		providers: p,
+		events:    e,
+	}
+}
+
+// sessionTouchInterval limits how often the last seen time of a session is updated.
+const sessionTouchInterval = time.Minute
+
+// IssueTokens will start a new session for the user on the client and return an access token with a refresh token for it.
+func (a *Auth) IssueTokens(ctx context.Context, userId string, client model.Client) (*model.Tokens, error) {
+	// Disabled accounts are refused before a session is recorded for them
+	if _, err := a.getAccount(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	session := &model.Session{UserID: &userId}
+	if client.UserAgent != "" {
+		session.UserAgent = &client.UserAgent
+	}
+	if client.IP != "" {
+		session.IP = &client.IP
+	}
+	session, err := a.store.InsertSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, _, err := a.createTokens(ctx, userId, session.ID)
+	return tokens, err
+}
+
+// Refresh will exchange a refresh token for a new pair of tokens. The presented refresh token is rotated,
+// and presenting it again revokes the whole session as the token is considered stolen.
+func (a *Auth) Refresh(ctx context.Context, refreshToken string, client model.Client) (*model.Tokens, error) {
+	t, err := a.getRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.RevokedAt != nil {
+		if t.ReplacedBy == nil {
+			return nil, ErrInvalidRefreshToken.Wrap(errors.ErrUnauthorized)
+		}
+		return nil, a.revokeReusedFamily(ctx, t)
+	}
+
+	if t.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidRefreshToken.Wrap(errors.ErrUnauthorized)
+	}
+
+	tokens, replacement, err := a.createTokens(ctx, *t.UserID, t.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.store.RevokeRefreshToken(ctx, *t.ID, replacement.ID); err != nil {
+		if errors.Is(err, authStore.ErrTokenNotRevoked) {
+			// The token was rotated concurrently, so it was used twice
+			return nil, a.revokeReusedFamily(ctx, t)
+		}
+		return nil, err
+	}
+
+	var ip *string
+	if client.IP != "" {
+		ip = &client.IP
+	}
+	if err := a.store.TouchSession(ctx, *t.FamilyID, ip, sessionTouchInterval); err != nil {
+		logging.From(ctx).Error("failed to update session", zap.Error(err))
+	}
+
+	return tokens, nil
+}
+
+// Logout will revoke the session the refresh token belongs to, invalidating its access tokens as well.
+func (a *Auth) Logout(ctx context.Context, refreshToken string) error {
+	t, err := a.getRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return a.store.RevokeFamily(ctx, *t.FamilyID)
+}
+
+// RevokeUserSessions will revoke every session of the user.
+func (a *Auth) RevokeUserSessions(ctx context.Context, userId string) error {
+	return a.store.RevokeUserRefreshTokens(ctx, userId)
+}
+
+// Authenticate will verify the access token from the Authorization header and check that its session is still active.
+// Personal access tokens are accepted as well.
+func (a *Auth) Authenticate(ctx context.Context, authHeader string) (*model.Claims, error) {
+	if pat := strings.TrimPrefix(authHeader, "Bearer "); isPersonalAccessToken(pat) {
+		return a.authenticatePersonalAccessToken(ctx, pat)
+	}
+
+	token, err := helpers.ParseToken(authHeader)
+	if err != nil {
+		return nil, ErrInvalidToken.Wrap(errors.ErrUnauthorized.Wrap(err))
+	}
+
+	userId, _ := token["sub"].(string)
+	sessionId, _ := token["jti"].(string)
+	role, _ := token["role"].(string)
+	if userId == "" || sessionId == "" || role == "" {
+		return nil, ErrInvalidToken.Wrap(errors.ErrUnauthorized)
+	}
+
+	active, err := a.store.IsFamilyActive(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrInvalidToken.Wrap(errors.ErrUnauthorized)
+	}
+
+	if err := a.store.TouchSession(ctx, sessionId, nil, sessionTouchInterval); err != nil {
+		logging.From(ctx).Error("failed to update session", zap.Error(err))
+	}
+
+	return &model.Claims{
+		UserID:    userId,
+		SessionID: sessionId,
+		Role:      role,
+	}, nil
+}
+
+// GetSessions returns the devices the user is signed in on.
+func (a *Auth) GetSessions(ctx context.Context, userId string) ([]model.Session, error) {
+	return a.store.GetActiveSessions(ctx, userId)
+}
+
+// RevokeSession will sign the user out of the session, its access tokens are refused right away.
+func (a *Auth) RevokeSession(ctx context.Context, userId string, sessionId string) error {
+	session, err := a.store.GetSession(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) || errors.Is(err, errors.ErrValidation) {
+			return ErrSessionNotFound.Wrap(errors.ErrNotFound)
+		}
+		return err
+	}
+	if *session.UserID != userId {
+		return ErrSessionNotFound.Wrap(errors.ErrNotFound)
+	}
+
+	return a.store.RevokeFamily(ctx, sessionId)
+}
+
+func (a *Auth) getAccount(ctx context.Context, userId string) (*model.Account, error) {
+	account, err := a.store.GetAccount(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if account.DisabledAt != nil {
+		return nil, ErrAccountDisabled.Wrap(errors.ErrForbidden)
+	}
+
+	return account, nil
+}
+
+func (a *Auth) getRefreshToken(ctx context.Context, refreshToken string) (*model.RefreshToken, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken.Wrap(errors.ErrUnauthorized)
+	}
+
+	t, err := a.store.GetRefreshToken(ctx, helpers.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken.Wrap(errors.ErrUnauthorized)
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (a *Auth) revokeReusedFamily(ctx context.Context, t *model.RefreshToken) error {
+	logging.From(ctx).Warn("refresh token reuse detected, revoking session",
+		zap.String("user_id", *t.UserID), zap.String("family_id", *t.FamilyID))
+
+	if err := a.store.RevokeFamily(ctx, *t.FamilyID); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused.Wrap(errors.ErrUnauthorized)
+}
+
+func (a *Auth) createTokens(ctx context.Context, userId string, familyId *string) (*model.Tokens, *model.RefreshToken, error) {
+	cfg := helpers.GetConfig(ctx).Auth
+
+	// The role is read on every refresh, so role changes apply within the access token lifetime
+	account, err := a.getAccount(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := helpers.GenerateToken()
+	if err != nil {
+		return nil, nil, errors.ErrUnknown.Wrap(err)
+	}
+	tokenHash := helpers.HashToken(refreshToken)
+	expiresAt := time.Now().UTC().Add(cfg.RefreshTokenTTL)
+
+	t, err := a.store.InsertRefreshToken(ctx, &model.RefreshToken{
+		UserID:    &userId,
+		FamilyID:  familyId,
+		TokenHash: &tokenHash,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, err := helpers.CreateJwtToken(userId, *t.FamilyID, *account.Role, cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &model.Tokens{
+		UserID:       userId,
+		AccessToken:  *accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(cfg.AccessTokenTTL.Seconds()),
+	}, t, nil
+}
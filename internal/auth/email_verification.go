@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// ErrInvalidVerificationToken is returned when an email verification token is unknown, expired or already used.
+const ErrInvalidVerificationToken = errors.Error("invalid_verification_token: verification link is invalid or expired")
+
+// CreateEmailVerificationToken will create a single-use token confirming that the user owns the email address.
+func (a *Auth) CreateEmailVerificationToken(ctx context.Context, userId string, email string) (string, error) {
+	token, err := helpers.GenerateToken()
+	if err != nil {
+		return "", errors.ErrUnknown.Wrap(err)
+	}
+	tokenHash := helpers.HashToken(token)
+	expiresAt := time.Now().UTC().Add(helpers.GetConfig(ctx).Auth.EmailVerifyTTL)
+
+	_, err = a.store.InsertEmailVerificationToken(ctx, &model.EmailVerificationToken{
+		UserID:    &userId,
+		Email:     &email,
+		TokenHash: &tokenHash,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// UseEmailVerificationToken will invalidate the verification token and return the user and the email it confirms.
+func (a *Auth) UseEmailVerificationToken(ctx context.Context, token string) (string, string, error) {
+	if token == "" {
+		return "", "", ErrInvalidVerificationToken.Wrap(errors.ErrValidation)
+	}
+
+	t, err := a.store.UseEmailVerificationToken(ctx, helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return "", "", ErrInvalidVerificationToken.Wrap(errors.ErrValidation)
+		}
+		return "", "", err
+	}
+
+	return *t.UserID, *t.Email, nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// ErrTooManyLoginAttempts is returned when logins to the account or from the IP are locked after too many failures.
+const ErrTooManyLoginAttempts = errors.Error("too_many_login_attempts: too many failed logins, try again later")
+
+// loginFailureWindow is how long failed logins count towards a lockout.
+const loginFailureWindow = 24 * time.Hour
+
+// CheckLoginAllowed returns an error if logins to the email or from the IP are locked out after too many failures.
+// Each failure over the limit doubles the lockout, up to the configured maximum.
+func (a *Auth) CheckLoginAllowed(ctx context.Context, email string, ip string) error {
+	cfg := helpers.GetConfig(ctx).Auth
+	now := time.Now().UTC()
+	since := now.Add(-loginFailureWindow)
+
+	f, err := a.store.GetAccountLoginFailures(ctx, normalizeEmail(email), since)
+	if err != nil {
+		return err
+	}
+	if lockedUntil(f, cfg.LoginMaxFailures, cfg.LoginLockout, cfg.LoginMaxLockout).After(now) {
+		return ErrTooManyLoginAttempts.Wrap(errors.ErrTooManyRequests)
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	f, err = a.store.GetIPLoginFailures(ctx, ip, since)
+	if err != nil {
+		return err
+	}
+	if lockedUntil(f, cfg.LoginMaxIPFailures, cfg.LoginLockout, cfg.LoginMaxLockout).After(now) {
+		return ErrTooManyLoginAttempts.Wrap(errors.ErrTooManyRequests)
+	}
+
+	return nil
+}
+
+// RecordLoginAttempt will add the login attempt to the history, userId is nil when no user has the email.
+func (a *Auth) RecordLoginAttempt(ctx context.Context, email string, userId *string, ip string, success bool) error {
+	email = normalizeEmail(email)
+	kind := model.LoginAttemptPassword
+	return a.store.InsertLoginAttempt(ctx, &model.LoginAttempt{
+		Email:   &email,
+		UserID:  userId,
+		IP:      &ip,
+		Kind:    &kind,
+		Success: &success,
+	})
+}
+
+// checkMFAAllowed returns an error if the user is locked out after too many wrong second factor codes.
+func (a *Auth) checkMFAAllowed(ctx context.Context, userId string) error {
+	cfg := helpers.GetConfig(ctx).Auth
+	now := time.Now().UTC()
+
+	f, err := a.store.GetMFAFailures(ctx, userId, now.Add(-loginFailureWindow))
+	if err != nil {
+		return err
+	}
+	if lockedUntil(f, cfg.LoginMaxFailures, cfg.LoginLockout, cfg.LoginMaxLockout).After(now) {
+		return ErrTooManyLoginAttempts.Wrap(errors.ErrTooManyRequests)
+	}
+
+	return nil
+}
+
+// recordMFAAttempt will add the second factor attempt of the user to the history.
+func (a *Auth) recordMFAAttempt(ctx context.Context, userId string, ip string, success bool) error {
+	kind := model.LoginAttemptMFA
+	return a.store.InsertLoginAttempt(ctx, &model.LoginAttempt{
+		UserID:  &userId,
+		IP:      &ip,
+		Kind:    &kind,
+		Success: &success,
+	})
+}
+
+// GetLoginAttempts returns the login attempt history matching the filter, newest first.
+func (a *Auth) GetLoginAttempts(ctx context.Context, filter model.LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	filter.Email = normalizeEmail(filter.Email)
+	return a.store.GetLoginAttempts(ctx, filter)
+}
+
+func lockedUntil(f *model.LoginFailures, maxFailures int, lockout time.Duration, maxLockout time.Duration) time.Time {
+	if f.Count < maxFailures || f.LastFailure == nil {
+		return time.Time{}
+	}
+
+	for i := maxFailures; i < f.Count && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLockout {
+		lockout = maxLockout
+	}
+
+	return f.LastFailure.Add(lockout)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,192 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RefreshToken represents a long-lived token which can be exchanged for a new access token.
+// Tokens issued for a single login share the same family, so the whole chain can be revoked at once.
+type RefreshToken struct {
+	ID         *string    `json:"id" db:"id"`
+	UserID     *string    `json:"user_id" db:"user_id"`
+	FamilyID   *string    `json:"family_id" db:"family_id"`
+	TokenHash  *string    `json:"-" db:"token_hash"`
+	ReplacedBy *string    `json:"replaced_by" db:"replaced_by"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt  *time.Time `json:"created_at" db:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// Tokens represents a pair of tokens issued to a user on login.
+type Tokens struct {
+	UserID       string `json:"-"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Session represents a device the user is signed in on.
+type Session struct {
+	ID         *string    `json:"id" db:"id"`
+	UserID     *string    `json:"-" db:"user_id"`
+	UserAgent  *string    `json:"user_agent" db:"user_agent"`
+	IP         *string    `json:"ip" db:"ip"`
+	CreatedAt  *time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt *time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// Client represents the device a request is made from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Claims represents the verified content of an access token.
+type Claims struct {
+	UserID    string
+	SessionID string
+	Role      string
+	// Scopes limit what a personal access token can do, nil for tokens of a session.
+	Scopes []string
+}
+
+// Account represents the state of the user's account relevant for issuing tokens.
+type Account struct {
+	Role       *string    `db:"role"`
+	DisabledAt *time.Time `db:"disabled_at"`
+}
+
+// PasswordResetToken represents a single-use token sent to a user who forgot their password.
+type PasswordResetToken struct {
+	ID        *string    `json:"id" db:"id"`
+	UserID    *string    `json:"user_id" db:"user_id"`
+	TokenHash *string    `json:"-" db:"token_hash"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt *time.Time `json:"created_at" db:"created_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+}
+
+// EmailVerificationToken represents a single-use token sent to confirm the ownership of an email address.
+type EmailVerificationToken struct {
+	ID        *string    `json:"id" db:"id"`
+	UserID    *string    `json:"user_id" db:"user_id"`
+	Email     *string    `json:"email" db:"email"`
+	TokenHash *string    `json:"-" db:"token_hash"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt *time.Time `json:"created_at" db:"created_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+}
+
+// Identity represents an account of a user at an external identity provider.
+type Identity struct {
+	ID          *string    `json:"id" db:"id"`
+	UserID      *string    `json:"user_id" db:"user_id"`
+	Provider    *string    `json:"provider" db:"provider"`
+	Subject     *string    `json:"-" db:"subject"`
+	Email       *string    `json:"email" db:"email"`
+	CreatedAt   *time.Time `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at" db:"last_login_at"`
+}
+
+// LoginState represents a sign in with an identity provider which was started but not finished yet.
+type LoginState struct {
+	StateHash    *string    `db:"state_hash"`
+	Provider     *string    `db:"provider"`
+	Nonce        *string    `db:"nonce"`
+	CodeVerifier *string    `db:"code_verifier"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+	CreatedAt    *time.Time `db:"created_at"`
+}
+
+// LoginAttemptKind is an enum providing which step of the login was tried.
+type LoginAttemptKind string
+
+const (
+	// LoginAttemptPassword represents a try of the email and password.
+	LoginAttemptPassword LoginAttemptKind = "password"
+	// LoginAttemptMFA represents a try of the second factor of a user who passed the password check.
+	LoginAttemptMFA LoginAttemptKind = "mfa"
+)
+
+// LoginAttempt represents a single try to sign in with email and password, or with the second factor.
+type LoginAttempt struct {
+	ID        *string           `json:"id" db:"id"`
+	Email     *string           `json:"email" db:"email"`
+	UserID    *string           `json:"user_id" db:"user_id"`
+	IP        *string           `json:"ip" db:"ip"`
+	Kind      *LoginAttemptKind `json:"kind" db:"kind"`
+	Success   *bool             `json:"success" db:"success"`
+	CreatedAt *time.Time        `json:"created_at" db:"created_at"`
+}
+
+// LoginFailures represents the failed login attempts counted for a lockout.
+type LoginFailures struct {
+	Count       int        `db:"count"`
+	LastFailure *time.Time `db:"last_failure"`
+}
+
+// LoginAttemptFilter represents the criteria for querying the login attempt history.
+type LoginAttemptFilter struct {
+	Email  string
+	IP     string
+	Offset int64
+	Limit  int64
+}
+
+// Token scopes a personal access token can be limited to.
+const (
+	// ScopeRead allows every read only request.
+	ScopeRead = "read"
+	// ScopeQuestsWrite allows creating, changing and sending quests.
+	ScopeQuestsWrite = "quests:write"
+	// ScopeMediaWrite allows uploading media.
+	ScopeMediaWrite = "media:write"
+)
+
+// PersonalAccessToken represents a long-lived token a user created for scripting the API.
+type PersonalAccessToken struct {
+	ID         *string         `json:"id" db:"id"`
+	UserID     *string         `json:"-" db:"user_id"`
+	Name       *string         `json:"name" db:"name"`
+	TokenHash  *string         `json:"-" db:"token_hash"`
+	Scopes     *pq.StringArray `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time      `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time      `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  *time.Time      `json:"created_at" db:"created_at"`
+	RevokedAt  *time.Time      `json:"-" db:"revoked_at"`
+}
+
+// TOTP represents the authenticator app of a user, used as the second factor once confirmed.
+type TOTP struct {
+	UserID       *string    `db:"user_id"`
+	Secret       *string    `db:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    *time.Time `db:"created_at"`
+}
+
+// TOTPEnrollment represents what an authenticator app needs to be set up, the URI is also rendered as a QR code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"`
+}
+
+// TwoFactorStatus represents whether the user has two-factor authentication enabled.
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// MFAChallenge represents a login which passed the password check and waits for the second factor.
+type MFAChallenge struct {
+	ID        *string    `db:"id"`
+	UserID    *string    `db:"user_id"`
+	TokenHash *string    `db:"token_hash"`
+	Attempts  int        `db:"attempts"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	CreatedAt *time.Time `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
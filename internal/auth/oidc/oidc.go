@@ -0,0 +1,318 @@
+// Package oidc implements the parts of OpenID Connect needed to sign users in with an external identity provider:
+// discovery, the authorization code flow with PKCE and ID token verification against the provider's JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/superhorsy/quest-app-backend/internal/core/config"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+)
+
+const (
+	// ErrUnknownProvider is returned when no provider is configured under the requested name.
+	ErrUnknownProvider = errors.Error("unknown_provider: identity provider is not configured")
+	// ErrDiscovery is returned when the discovery document of the provider can't be loaded.
+	ErrDiscovery = errors.Error("oidc_discovery: failed to load provider configuration")
+	// ErrExchange is returned when the provider refuses to exchange the authorization code.
+	ErrExchange = errors.Error("oidc_exchange: failed to exchange authorization code")
+	// ErrInvalidIDToken is returned when the ID token fails verification.
+	ErrInvalidIDToken = errors.Error("invalid_id_token: id token is invalid")
+)
+
+const (
+	httpTimeout = 10 * time.Second
+	// clockSkew is tolerated between our clock and the clock of the provider.
+	clockSkew = time.Minute
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Claims represents the verified identity of a user returned by a provider.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Name          string
+	Nickname      string
+}
+
+// discovery represents the fields of the provider's /.well-known/openid-configuration we rely on.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// Provider represents a configured OpenID Connect identity provider.
+type Provider struct {
+	cfg    config.OIDCProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      *keySet
+}
+
+// Providers represents the identity providers users can sign in with, keyed by name.
+type Providers map[string]*Provider
+
+// NewProviders will instantiate providers from the configuration. Discovery is done lazily on first use,
+// so a provider which is down doesn't prevent the app from starting.
+func NewProviders(cfgs []config.OIDCProviderConfig) Providers {
+	p := Providers{}
+	for _, cfg := range cfgs {
+		p[cfg.Name] = &Provider{
+			cfg:    cfg,
+			client: &http.Client{Timeout: httpTimeout},
+		}
+	}
+	return p
+}
+
+// Get returns the provider configured under the name.
+func (p Providers) Get(name string) (*Provider, error) {
+	provider, ok := p[name]
+	if !ok {
+		return nil, ErrUnknownProvider.Wrap(errors.ErrNotFound)
+	}
+	return provider, nil
+}
+
+// Names returns the names of all configured providers.
+func (p Providers) Names() []string {
+	names := []string{}
+	for name := range p {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Name returns the name the provider is configured under.
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns the URL the user has to be sent to in order to sign in with the provider.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	v := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange will exchange the authorization code for tokens and return the verified claims of the ID token.
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Claims, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if secret := p.cfg.ClientSecret(); secret != "" {
+		form.Set("client_secret", secret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, ErrExchange.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, ErrExchange.Wrap(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, ErrExchange.Wrap(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		// The code is provided by the user, so a refusal is most likely caused by a bad or reused code
+		return nil, ErrExchange.Wrap(errors.ErrValidation.Wrap(fmt.Errorf("status %d: %s", res.StatusCode, body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, ErrExchange.Wrap(err)
+	}
+	if tokens.IDToken == "" {
+		return nil, ErrExchange.Wrap(errors.New("no id_token in token response"))
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// VerifyIDToken will verify the signature, issuer, audience, lifetime and nonce of the ID token and return its claims.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*Claims, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwt.Parser{
+		ValidMethods:         []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
+		SkipClaimsValidation: true,
+	}
+	claims := jwt.MapClaims{}
+	_, err = parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, ErrInvalidIDToken.Wrap(errors.ErrUnauthorized.Wrap(err))
+	}
+
+	if err := p.validateClaims(claims, d.Issuer, nonce); err != nil {
+		return nil, ErrInvalidIDToken.Wrap(errors.ErrUnauthorized.Wrap(err))
+	}
+
+	c := &Claims{}
+	c.Subject, _ = claims["sub"].(string)
+	c.Email, _ = claims["email"].(string)
+	c.GivenName, _ = claims["given_name"].(string)
+	c.FamilyName, _ = claims["family_name"].(string)
+	c.Name, _ = claims["name"].(string)
+	c.Nickname, _ = claims["preferred_username"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = v
+	case string:
+		// Some providers send booleans as strings
+		c.EmailVerified = v == "true"
+	}
+
+	return c, nil
+}
+
+func (p *Provider) validateClaims(claims jwt.MapClaims, issuer string, nonce string) error {
+	now := time.Now()
+
+	if iss, _ := claims["iss"].(string); iss != issuer {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("no subject")
+	}
+	if !hasAudience(claims["aud"], p.cfg.ClientID) {
+		return errors.New("token is issued for another client")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.Add(-clockSkew).Unix() > int64(exp) {
+		return errors.New("token is expired")
+	}
+	if iat, ok := claims["iat"].(float64); ok && now.Add(clockSkew).Unix() < int64(iat) {
+		return errors.New("token is issued in the future")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return errors.New("nonce mismatch")
+	}
+
+	return nil
+}
+
+func hasAudience(aud interface{}, clientId string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientId
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	d := &discovery{}
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, ErrDiscovery.Wrap(err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, ErrDiscovery.Wrap(fmt.Errorf("issuer %q doesn't match configured %q", d.Issuer, p.cfg.Issuer))
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JwksURI == "" {
+		return nil, ErrDiscovery.Wrap(errors.New("required endpoints are missing"))
+	}
+
+	p.discovery = d
+	return d, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, res.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(target)
+}
@@ -0,0 +1,227 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/superhorsy/quest-app-backend/internal/core/config"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+)
+
+const (
+	testClientID = "quest-app"
+	testKid      = "key-1"
+	testNonce    = "nonce-1"
+)
+
+// newTestProvider starts a provider serving its discovery document and a JWKS with a single RSA key.
+func newTestProvider(t *testing.T) (*Provider, *rsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(discovery{
+			Issuer:                srv.URL,
+			AuthorizationEndpoint: srv.URL + "/authorize",
+			TokenEndpoint:         srv.URL + "/token",
+			JwksURI:               srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string][]jwk{"keys": {{
+			Kty: "RSA",
+			Kid: testKid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	providers := NewProviders([]config.OIDCProviderConfig{{
+		Name:        "test",
+		Issuer:      srv.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/callback",
+	}})
+
+	return providers["test"], key, srv.URL
+}
+
+func validClaims(issuer string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            issuer,
+		"sub":            "subject-1",
+		"aud":            testClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          testNonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider, key, issuer := newTestProvider(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		nonce   string
+		wantErr string
+	}{
+		{
+			name: "valid token",
+			token: func() string {
+				return sign(t, jwt.SigningMethodRS256, testKid, validClaims(issuer), key)
+			},
+			nonce: testNonce,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims(issuer)
+				claims["iss"] = "https://evil.example.com"
+				return sign(t, jwt.SigningMethodRS256, testKid, claims, key)
+			},
+			nonce:   testNonce,
+			wantErr: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims(issuer)
+				claims["aud"] = []string{"another-client"}
+				return sign(t, jwt.SigningMethodRS256, testKid, claims, key)
+			},
+			nonce:   testNonce,
+			wantErr: "issued for another client",
+		},
+		{
+			name: "bad nonce",
+			token: func() string {
+				return sign(t, jwt.SigningMethodRS256, testKid, validClaims(issuer), key)
+			},
+			nonce:   "another-nonce",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				claims := validClaims(issuer)
+				claims["exp"] = time.Now().Add(-clockSkew - time.Minute).Unix()
+				return sign(t, jwt.SigningMethodRS256, testKid, claims, key)
+			},
+			nonce:   testNonce,
+			wantErr: "token is expired",
+		},
+		{
+			name: "hmac signed with the public key",
+			token: func() string {
+				pub, err := json.Marshal(key.PublicKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return sign(t, jwt.SigningMethodHS256, testKid, validClaims(issuer), pub)
+			},
+			nonce:   testNonce,
+			wantErr: "signing method HS256 is invalid",
+		},
+		{
+			name: "alg doesn't match the key",
+			token: func() string {
+				return sign(t, jwt.SigningMethodES256, testKid, validClaims(issuer), ecKey)
+			},
+			nonce:   testNonce,
+			wantErr: "key is of invalid type",
+		},
+		{
+			name: "unknown kid",
+			token: func() string {
+				return sign(t, jwt.SigningMethodRS256, "key-2", validClaims(issuer), key)
+			},
+			nonce:   testNonce,
+			wantErr: `unknown key "key-2"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.VerifyIDToken(context.Background(), tt.token(), tt.nonce)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if claims.Subject != "subject-1" || claims.Email != "user@example.com" || !claims.EmailVerified {
+					t.Errorf("unexpected claims: %+v", claims)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("expected %v, got %v", ErrInvalidIDToken, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error to contain %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(discovery{
+			Issuer:                "https://evil.example.com",
+			AuthorizationEndpoint: "https://evil.example.com/authorize",
+			TokenEndpoint:         "https://evil.example.com/token",
+			JwksURI:               "https://evil.example.com/jwks",
+		})
+	}))
+	defer srv.Close()
+
+	provider := NewProviders([]config.OIDCProviderConfig{{Name: "test", Issuer: srv.URL, ClientID: testClientID}})["test"]
+
+	if _, err := provider.AuthCodeURL(context.Background(), "state", testNonce, "verifier"); !errors.Is(err, ErrDiscovery) {
+		t.Fatalf("expected %v, got %v", ErrDiscovery, err)
+	}
+}
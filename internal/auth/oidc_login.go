@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/auth/oidc"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// ErrInvalidLoginState is returned when the sign in with an identity provider is unknown, expired or already finished.
+const ErrInvalidLoginState = errors.Error("invalid_login_state: sign in has expired, please try again")
+
+// loginStateTTL is how long the user has to sign in at the identity provider and come back.
+const loginStateTTL = 10 * time.Minute
+
+// OIDCProviders returns the names of the identity providers users can sign in with.
+func (a *Auth) OIDCProviders() []string {
+	names := a.providers.Names()
+	sort.Strings(names)
+	return names
+}
+
+// StartOIDCLogin will start a sign in with the identity provider and return the URL the user has to be sent to,
+// together with the state the callback has to come back with.
+func (a *Auth) StartOIDCLogin(ctx context.Context, providerName string) (string, string, error) {
+	p, err := a.providers.Get(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	values := make([]string, 3)
+	for i := range values {
+		if values[i], err = helpers.GenerateToken(); err != nil {
+			return "", "", errors.ErrUnknown.Wrap(err)
+		}
+	}
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	stateHash := helpers.HashToken(state)
+	expiresAt := time.Now().UTC().Add(loginStateTTL)
+	err = a.store.InsertLoginState(ctx, &model.LoginState{
+		StateHash:    &stateHash,
+		Provider:     &providerName,
+		Nonce:        &nonce,
+		CodeVerifier: &codeVerifier,
+		ExpiresAt:    &expiresAt,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// FinishOIDCLogin will exchange the authorization code the user came back with and return the verified identity.
+func (a *Auth) FinishOIDCLogin(ctx context.Context, providerName string, code string, state string) (*oidc.Claims, error) {
+	p, err := a.providers.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == "" || state == "" {
+		return nil, ErrInvalidLoginState.Wrap(errors.ErrValidation)
+	}
+
+	ls, err := a.store.TakeLoginState(ctx, providerName, helpers.HashToken(state))
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, ErrInvalidLoginState.Wrap(errors.ErrValidation)
+		}
+		return nil, err
+	}
+
+	return p.Exchange(ctx, code, *ls.CodeVerifier, *ls.Nonce)
+}
+
+// GetIdentity will retrieve the identity linked to the provider's user.
+func (a *Auth) GetIdentity(ctx context.Context, providerName string, subject string) (*model.Identity, error) {
+	return a.store.GetIdentity(ctx, providerName, subject)
+}
+
+// LinkIdentity will link the identity of the provider's user to the user, so they can sign in with it from now on.
+func (a *Auth) LinkIdentity(ctx context.Context, userId string, providerName string, claims *oidc.Claims) (*model.Identity, error) {
+	i := &model.Identity{
+		UserID:   &userId,
+		Provider: &providerName,
+		Subject:  &claims.Subject,
+	}
+	if claims.Email != "" {
+		i.Email = &claims.Email
+	}
+
+	return a.store.InsertIdentity(ctx, i)
+}
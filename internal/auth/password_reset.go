@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// ErrInvalidResetToken is returned when a password reset token is unknown, expired or already used.
+const ErrInvalidResetToken = errors.Error("invalid_reset_token: password reset link is invalid or expired")
+
+// CreatePasswordResetToken will create a single-use password reset token for the user.
+// Only the hash of the token is stored, the token itself has to be delivered to the user.
+func (a *Auth) CreatePasswordResetToken(ctx context.Context, userId string) (string, error) {
+	token, err := helpers.GenerateToken()
+	if err != nil {
+		return "", errors.ErrUnknown.Wrap(err)
+	}
+	tokenHash := helpers.HashToken(token)
+	expiresAt := time.Now().UTC().Add(helpers.GetConfig(ctx).Auth.PasswordResetTTL)
+
+	_, err = a.store.InsertPasswordResetToken(ctx, &model.PasswordResetToken{
+		UserID:    &userId,
+		TokenHash: &tokenHash,
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// UsePasswordResetToken will invalidate the password reset token and return the id of the user it was issued to.
+func (a *Auth) UsePasswordResetToken(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", ErrInvalidResetToken.Wrap(errors.ErrValidation)
+	}
+
+	t, err := a.store.UsePasswordResetToken(ctx, helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return "", ErrInvalidResetToken.Wrap(errors.ErrValidation)
+		}
+		return "", err
+	}
+
+	return *t.UserID, nil
+}
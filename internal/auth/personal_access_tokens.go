@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	authStore "github.com/superhorsy/quest-app-backend/internal/auth/store"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	// ErrInvalidScope is returned when a personal access token is requested without scopes or with an unknown one.
+	ErrInvalidScope = errors.Error("invalid_scope: scopes must be some of read, quests:write, media:write")
+	// ErrInvalidTokenName is returned when a personal access token is requested without a name.
+	ErrInvalidTokenName = errors.Error("invalid_token_name: token name is required")
+	// ErrInvalidTokenExpiry is returned when a personal access token is requested with an expiry in the past.
+	ErrInvalidTokenExpiry = errors.Error("invalid_token_expiry: token expiry must be in the future")
+	// ErrPersonalAccessTokenNotFound is returned when the user has no personal access token with the id.
+	ErrPersonalAccessTokenNotFound = errors.Error("personal_access_token_not_found: token not found")
+)
+
+// personalAccessTokenPrefix tells personal access tokens apart from JWTs, and makes leaked tokens easy to scan for.
+const personalAccessTokenPrefix = "qpat_"
+
+// CreatePersonalAccessToken will create a named token limited to the scopes, which expires at expiresAt if set.
+// The token itself is returned only here, just its hash is stored.
+func (a *Auth) CreatePersonalAccessToken(ctx context.Context, userId string, name string, scopes []string, expiresAt *time.Time) (*model.PersonalAccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrInvalidTokenName.Wrap(errors.ErrValidation)
+	}
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidScope.Wrap(errors.ErrValidation)
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case model.ScopeRead, model.ScopeQuestsWrite, model.ScopeMediaWrite:
+		default:
+			return nil, "", ErrInvalidScope.Wrap(errors.ErrValidation)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidTokenExpiry.Wrap(errors.ErrValidation)
+	}
+
+	secret, err := helpers.GenerateToken()
+	if err != nil {
+		return nil, "", errors.ErrUnknown.Wrap(err)
+	}
+	token := personalAccessTokenPrefix + secret
+	tokenHash := helpers.HashToken(token)
+	scopesArray := pq.StringArray(scopes)
+
+	t, err := a.store.InsertPersonalAccessToken(ctx, &model.PersonalAccessToken{
+		UserID:    &userId,
+		Name:      &name,
+		TokenHash: &tokenHash,
+		Scopes:    &scopesArray,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return t, token, nil
+}
+
+// GetPersonalAccessTokens returns the personal access tokens of the user which weren't revoked.
+func (a *Auth) GetPersonalAccessTokens(ctx context.Context, userId string) ([]model.PersonalAccessToken, error) {
+	return a.store.GetUserPersonalAccessTokens(ctx, userId)
+}
+
+// RevokePersonalAccessTokens will revoke every personal access token of the user, like after a password reset.
+func (a *Auth) RevokePersonalAccessTokens(ctx context.Context, userId string) error {
+	return a.store.RevokeUserPersonalAccessTokens(ctx, userId)
+}
+
+// RevokePersonalAccessToken will revoke the personal access token of the user.
+func (a *Auth) RevokePersonalAccessToken(ctx context.Context, userId string, id string) error {
+	err := a.store.RevokePersonalAccessToken(ctx, userId, id)
+	if errors.Is(err, authStore.ErrTokenNotRevoked) || errors.Is(err, errors.ErrValidation) {
+		return ErrPersonalAccessTokenNotFound.Wrap(errors.ErrNotFound)
+	}
+	return err
+}
+
+func isPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+func (a *Auth) authenticatePersonalAccessToken(ctx context.Context, token string) (*model.Claims, error) {
+	t, err := a.store.GetPersonalAccessToken(ctx, helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, ErrInvalidToken.Wrap(errors.ErrUnauthorized)
+		}
+		return nil, err
+	}
+	if t.RevokedAt != nil || (t.ExpiresAt != nil && t.ExpiresAt.Before(time.Now())) {
+		return nil, ErrInvalidToken.Wrap(errors.ErrUnauthorized)
+	}
+
+	account, err := a.getAccount(ctx, *t.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.store.TouchPersonalAccessToken(ctx, *t.ID, sessionTouchInterval); err != nil {
+		logging.From(ctx).Error("failed to update personal access token", zap.Error(err))
+	}
+
+	return &model.Claims{
+		UserID: *t.UserID,
+		Role:   *account.Role,
+		Scopes: *t.Scopes,
+	}, nil
+}
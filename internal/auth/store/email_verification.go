@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// InsertEmailVerificationToken will add a new email verification token, invalidating any token previously sent to the user.
+func (s *Store) InsertEmailVerificationToken(ctx context.Context, t *model.EmailVerificationToken) (*model.EmailVerificationToken, error) {
+	t.CreatedAt = helpers.TimeNow()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE email_verification_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`, t.CreatedAt, t.UserID)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`INSERT INTO 
+		email_verification_tokens(user_id, email, token_hash, expires_at, created_at) 
+		VALUES (:user_id, :email, :token_hash, :expires_at, :created_at) 
+		RETURNING *`, t)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrUnknown
+	}
+
+	createdToken := &model.EmailVerificationToken{}
+
+	if err := res.StructScan(createdToken); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return createdToken, nil
+}
+
+// UseEmailVerificationToken will mark an unused and not expired token as used and return it.
+func (s *Store) UseEmailVerificationToken(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error) {
+	var t model.EmailVerificationToken
+
+	now := helpers.TimeNow()
+	err := s.db.GetContext(ctx, &t,
+		`UPDATE email_verification_tokens SET used_at = $1 
+		WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1 
+		RETURNING *`, now, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// InsertLoginState will save a sign in started with an identity provider.
+func (s *Store) InsertLoginState(ctx context.Context, state *model.LoginState) error {
+	state.CreatedAt = helpers.TimeNow()
+
+	// Abandoned sign ins are cleaned up along the way
+	_, err := s.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < $1`, state.CreatedAt)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	_, err = s.db.NamedExecContext(ctx,
+		`INSERT INTO 
+		oidc_login_states(state_hash, provider, nonce, code_verifier, expires_at, created_at) 
+		VALUES (:state_hash, :provider, :nonce, :code_verifier, :expires_at, :created_at)`, state)
+
+	return checkWriteError(err)
+}
+
+// TakeLoginState will delete a not expired sign in of the provider and return it, so it can be finished only once.
+func (s *Store) TakeLoginState(ctx context.Context, provider string, stateHash string) (*model.LoginState, error) {
+	var state model.LoginState
+
+	err := s.db.GetContext(ctx, &state,
+		`DELETE FROM oidc_login_states 
+		WHERE state_hash = $1 AND provider = $2 AND expires_at > $3 
+		RETURNING *`, stateHash, provider, helpers.TimeNow())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &state, nil
+}
+
+// GetIdentity will retrieve the identity of a provider's user and record the sign in with it.
+func (s *Store) GetIdentity(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	var i model.Identity
+
+	err := s.db.GetContext(ctx, &i,
+		`UPDATE user_identities SET last_login_at = $1 
+		WHERE provider = $2 AND subject = $3 
+		RETURNING *`, helpers.TimeNow(), provider, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &i, nil
+}
+
+// InsertIdentity will link the identity of a provider's user to a user.
+func (s *Store) InsertIdentity(ctx context.Context, i *model.Identity) (*model.Identity, error) {
+	i.CreatedAt = helpers.TimeNow()
+	i.LastLoginAt = i.CreatedAt
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`INSERT INTO 
+		user_identities(user_id, provider, subject, email, created_at, last_login_at) 
+		VALUES (:user_id, :provider, :subject, :email, :created_at, :last_login_at) 
+		RETURNING *`, i)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrUnknown
+	}
+
+	createdIdentity := &model.Identity{}
+
+	if err := res.StructScan(createdIdentity); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return createdIdentity, nil
+}
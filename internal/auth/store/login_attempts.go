@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// InsertLoginAttempt will add the login attempt to the history.
+// Attempts without an email are recorded with the email of the user.
+func (s *Store) InsertLoginAttempt(ctx context.Context, a *model.LoginAttempt) error {
+	a.CreatedAt = helpers.TimeNow()
+
+	_, err := s.db.NamedExecContext(ctx,
+		`INSERT INTO 
+		login_attempts(email, user_id, ip, kind, success, created_at) 
+		VALUES (COALESCE(:email, (SELECT email FROM users WHERE id = :user_id)), :user_id, :ip, :kind, :success, :created_at)`, a)
+
+	return checkWriteError(err)
+}
+
+// GetAccountLoginFailures counts failed passwords for the email since its last successful login, ignoring those before since.
+func (s *Store) GetAccountLoginFailures(ctx context.Context, email string, since time.Time) (*model.LoginFailures, error) {
+	var f model.LoginFailures
+
+	err := s.db.GetContext(ctx, &f,
+		`SELECT count(*) AS count, max(created_at) AS last_failure FROM login_attempts
+		WHERE email = $1 AND kind = 'password' AND NOT success AND created_at > $2
+		AND created_at > COALESCE((SELECT max(created_at) FROM login_attempts WHERE email = $1 AND kind = 'password' AND success), $2)`,
+		email, since)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// GetMFAFailures counts failed second factor codes of the user since their last accepted one, ignoring those before since.
+// A correct password doesn't reset the count, so codes can't be guessed by logging in again.
+func (s *Store) GetMFAFailures(ctx context.Context, userId string, since time.Time) (*model.LoginFailures, error) {
+	var f model.LoginFailures
+
+	err := s.db.GetContext(ctx, &f,
+		`SELECT count(*) AS count, max(created_at) AS last_failure FROM login_attempts
+		WHERE user_id = $1 AND kind = 'mfa' AND NOT success AND created_at > $2
+		AND created_at > COALESCE((SELECT max(created_at) FROM login_attempts WHERE user_id = $1 AND kind = 'mfa' AND success), $2)`,
+		userId, since)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// GetIPLoginFailures counts failed passwords and codes from the IP to any account, ignoring those before since.
+func (s *Store) GetIPLoginFailures(ctx context.Context, ip string, since time.Time) (*model.LoginFailures, error) {
+	var f model.LoginFailures
+
+	err := s.db.GetContext(ctx, &f,
+		`SELECT count(*) AS count, max(created_at) AS last_failure FROM login_attempts
+		WHERE ip = $1 AND NOT success AND created_at > $2`,
+		ip, since)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// GetLoginAttempts returns the login attempt history matching the filter, newest first.
+func (s *Store) GetLoginAttempts(ctx context.Context, filter model.LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	var whereClauses []string
+	var values []interface{}
+
+	if filter.Email != "" {
+		values = append(values, filter.Email)
+		whereClauses = append(whereClauses, fmt.Sprintf("email = $%d", len(values)))
+	}
+	if filter.IP != "" {
+		values = append(values, filter.IP)
+		whereClauses = append(whereClauses, fmt.Sprintf("ip = $%d", len(values)))
+	}
+
+	where := ""
+	if len(whereClauses) > 0 {
+		where = " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+
+	limitClause := ""
+	if filter.Limit > 0 {
+		limitClause = fmt.Sprintf(" LIMIT %d OFFSET %d", filter.Limit, filter.Offset)
+	}
+
+	a := []model.LoginAttempt{}
+	err := s.db.SelectContext(ctx, &a, "SELECT * FROM login_attempts"+where+" ORDER BY created_at DESC"+limitClause, values...)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
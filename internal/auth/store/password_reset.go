@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// InsertPasswordResetToken will add a new password reset token, invalidating any token previously sent to the user.
+func (s *Store) InsertPasswordResetToken(ctx context.Context, t *model.PasswordResetToken) (*model.PasswordResetToken, error) {
+	t.CreatedAt = helpers.TimeNow()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE password_reset_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`, t.CreatedAt, t.UserID)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`INSERT INTO 
+		password_reset_tokens(user_id, token_hash, expires_at, created_at) 
+		VALUES (:user_id, :token_hash, :expires_at, :created_at) 
+		RETURNING *`, t)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrUnknown
+	}
+
+	createdToken := &model.PasswordResetToken{}
+
+	if err := res.StructScan(createdToken); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return createdToken, nil
+}
+
+// UsePasswordResetToken will mark an unused and not expired token as used and return it.
+// Marking is done in a single statement, so the token can't be used twice by concurrent requests.
+func (s *Store) UsePasswordResetToken(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error) {
+	var t model.PasswordResetToken
+
+	now := helpers.TimeNow()
+	err := s.db.GetContext(ctx, &t,
+		`UPDATE password_reset_tokens SET used_at = $1 
+		WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1 
+		RETURNING *`, now, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// InsertPersonalAccessToken will add a new personal access token to the database.
+func (s *Store) InsertPersonalAccessToken(ctx context.Context, t *model.PersonalAccessToken) (*model.PersonalAccessToken, error) {
+	t.CreatedAt = helpers.TimeNow()
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`INSERT INTO 
+		personal_access_tokens(user_id, "name", token_hash, scopes, expires_at, created_at) 
+		VALUES (:user_id, :name, :token_hash, :scopes, :expires_at, :created_at) 
+		RETURNING *`, t)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrUnknown
+	}
+
+	createdToken := &model.PersonalAccessToken{}
+
+	if err := res.StructScan(createdToken); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return createdToken, nil
+}
+
+// GetPersonalAccessToken will retrieve a personal access token via its hash.
+func (s *Store) GetPersonalAccessToken(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	var t model.PersonalAccessToken
+
+	if err := s.db.GetContext(ctx, &t, "SELECT * FROM personal_access_tokens WHERE token_hash = $1", tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &t, nil
+}
+
+// GetUserPersonalAccessTokens returns the personal access tokens of the user which weren't revoked, newest first.
+func (s *Store) GetUserPersonalAccessTokens(ctx context.Context, userId string) ([]model.PersonalAccessToken, error) {
+	tokens := []model.PersonalAccessToken{}
+
+	err := s.db.SelectContext(ctx, &tokens,
+		`SELECT * FROM personal_access_tokens WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`, userId)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RevokePersonalAccessToken will revoke the personal access token of the user.
+func (s *Store) RevokePersonalAccessToken(ctx context.Context, userId string, id string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE personal_access_tokens SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`,
+		helpers.TimeNow(), id, userId)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrTokenNotRevoked.Wrap(errors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// RevokeUserPersonalAccessTokens will revoke every personal access token of the user.
+func (s *Store) RevokeUserPersonalAccessTokens(ctx context.Context, userId string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE personal_access_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		helpers.TimeNow(), userId)
+	return checkWriteError(err)
+}
+
+// TouchPersonalAccessToken will update when the token was last used, at most once per interval.
+func (s *Store) TouchPersonalAccessToken(ctx context.Context, id string, interval time.Duration) error {
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE personal_access_tokens SET last_used_at = $1 
+		WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`, now, id, now.Add(-interval))
+
+	return checkWriteError(err)
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// InsertSession will add a new session to the database.
+func (s *Store) InsertSession(ctx context.Context, session *model.Session) (*model.Session, error) {
+	session.CreatedAt = helpers.TimeNow()
+	session.LastSeenAt = session.CreatedAt
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`INSERT INTO 
+		sessions(user_id, user_agent, ip, created_at, last_seen_at) 
+		VALUES (:user_id, :user_agent, :ip, :created_at, :last_seen_at) 
+		RETURNING *`, session)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrUnknown
+	}
+
+	createdSession := &model.Session{}
+
+	if err := res.StructScan(createdSession); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return createdSession, nil
+}
+
+// TouchSession will update when the session was last seen, at most once per interval, and its IP if set.
+func (s *Store) TouchSession(ctx context.Context, id string, ip *string, interval time.Duration) error {
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE sessions SET last_seen_at = $1, ip = COALESCE($2, ip) 
+		WHERE id = $3 AND (last_seen_at < $4 OR $2 IS NOT NULL)`, now, ip, id, now.Add(-interval))
+
+	return checkWriteError(err)
+}
+
+// GetSession will retrieve a session via its ID.
+func (s *Store) GetSession(ctx context.Context, id string) (*model.Session, error) {
+	var session model.Session
+
+	if err := s.db.GetContext(ctx, &session, "SELECT * FROM sessions WHERE id = $1", id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &session, nil
+}
+
+// GetActiveSessions returns the sessions of the user which weren't signed out and haven't expired, last seen first.
+func (s *Store) GetActiveSessions(ctx context.Context, userId string) ([]model.Session, error) {
+	sessions := []model.Session{}
+
+	err := s.db.SelectContext(ctx, &sessions,
+		`SELECT s.* FROM sessions s 
+		WHERE s.user_id = $1 
+		AND EXISTS(SELECT 1 FROM refresh_tokens t WHERE t.family_id = s.id AND t.revoked_at IS NULL AND t.expires_at > $2)
+		ORDER BY s.last_seen_at DESC`, userId, helpers.TimeNow())
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
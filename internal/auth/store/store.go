@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+const (
+	// ErrInvalidID is returned when the ID is not a valid UUID or is empty.
+	ErrInvalidID = errors.Error("invalid_id: id is invalid")
+	// ErrTokenNotRevoked is returned when a token can't be found or is already revoked.
+	ErrTokenNotRevoked = errors.Error("token_not_revoked: token is already revoked")
+)
+
+// DB represents a type for interfacing with a database.
+type DB interface {
+	NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+}
+
+// Store provides functionality for working with a database.
+type Store struct {
+	db DB
+}
+
+// New will instantiate a new instance of Store.
+func New(db DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// InsertRefreshToken will add a new refresh token to the database, starting a new family if none is set.
+func (s *Store) InsertRefreshToken(ctx context.Context, t *model.RefreshToken) (*model.RefreshToken, error) {
+	t.CreatedAt = helpers.TimeNow()
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`INSERT INTO 
+		refresh_tokens(user_id, family_id, token_hash, expires_at, created_at) 
+		VALUES (:user_id, COALESCE(:family_id, uuid_generate_v4()), :token_hash, :expires_at, :created_at) 
+		RETURNING *`, t)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrUnknown
+	}
+
+	createdToken := &model.RefreshToken{}
+
+	if err := res.StructScan(createdToken); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return createdToken, nil
+}
+
+// GetRefreshToken will retrieve a refresh token via the hash of its value.
+func (s *Store) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var t model.RefreshToken
+
+	if err := s.db.GetContext(ctx, &t, "SELECT * FROM refresh_tokens WHERE token_hash = $1", tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &t, nil
+}
+
+// RevokeRefreshToken will revoke a single refresh token, optionally recording the token it was rotated into.
+// It fails with ErrTokenNotRevoked if the token was already revoked, e.g. by a concurrent refresh.
+func (s *Store) RevokeRefreshToken(ctx context.Context, id string, replacedBy *string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1, replaced_by = $2 WHERE id = $3 AND revoked_at IS NULL`,
+		helpers.TimeNow(), replacedBy, id)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrTokenNotRevoked
+	}
+
+	return nil
+}
+
+// RevokeFamily will revoke every refresh token issued for the same login.
+func (s *Store) RevokeFamily(ctx context.Context, familyId string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL`,
+		helpers.TimeNow(), familyId)
+
+	return checkWriteError(err)
+}
+
+// RevokeUserRefreshTokens will revoke every refresh token issued to the user.
+func (s *Store) RevokeUserRefreshTokens(ctx context.Context, userId string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		helpers.TimeNow(), userId)
+
+	return checkWriteError(err)
+}
+
+// IsFamilyActive checks if the family still has a refresh token which is neither revoked nor expired.
+func (s *Store) IsFamilyActive(ctx context.Context, familyId string) (bool, error) {
+	var active bool
+
+	err := s.db.GetContext(ctx, &active,
+		`SELECT EXISTS(SELECT 1 FROM refresh_tokens WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > $2)`,
+		familyId, helpers.TimeNow())
+	if err = checkWriteError(err); err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+// GetAccount returns the role of the user and whether their account is disabled.
+func (s *Store) GetAccount(ctx context.Context, userId string) (*model.Account, error) {
+	var a model.Account
+
+	if err := s.db.GetContext(ctx, &a, "SELECT role, disabled_at FROM users WHERE id = $1", userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &a, nil
+}
+
+func checkWriteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation", "not_null_violation", "foreign_key_violation":
+			return errors.ErrValidation.Wrap(err)
+		case "invalid_text_representation":
+			if strings.Contains(pqErr.Error(), "uuid") {
+				return ErrInvalidID.Wrap(errors.ErrValidation.Wrap(err))
+			}
+		}
+	}
+
+	return errors.ErrUnknown.Wrap(err)
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+// ErrCodeNotUsed is returned when a one-time code was already used or can't be found.
+const ErrCodeNotUsed = errors.Error("code_not_used: code is already used")
+
+// UpsertTOTP will store a new secret for the user, replacing an enrollment which wasn't confirmed yet.
+// A confirmed secret is never replaced, ErrValidation is returned instead.
+func (s *Store) UpsertTOTP(ctx context.Context, userId string, secret string) error {
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO user_totp(user_id, secret, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_totp.confirmed_at IS NULL`, userId, secret, helpers.TimeNow())
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return errors.ErrValidation
+	}
+
+	return nil
+}
+
+// GetTOTP will retrieve the authenticator app secret of the user.
+func (s *Store) GetTOTP(ctx context.Context, userId string) (*model.TOTP, error) {
+	var t model.TOTP
+
+	if err := s.db.GetContext(ctx, &t, "SELECT * FROM user_totp WHERE user_id = $1", userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &t, nil
+}
+
+// ConfirmTOTP will enable the secret of the user and store the recovery codes, replacing any previous ones.
+func (s *Store) ConfirmTOTP(ctx context.Context, userId string, step int64, codeHashes []string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE user_totp SET confirmed_at = $1, last_used_step = $2 WHERE user_id = $3 AND confirmed_at IS NULL`,
+		helpers.TimeNow(), step, userId)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrCodeNotUsed
+	}
+
+	return s.ReplaceRecoveryCodes(ctx, userId, codeHashes)
+}
+
+// UseTOTPStep will record the time step of an accepted code. It fails with ErrCodeNotUsed if the step or a later one
+// was already used, so the same code can't be accepted twice by concurrent requests.
+func (s *Store) UseTOTPStep(ctx context.Context, userId string, step int64) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE user_totp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`, step, userId)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrCodeNotUsed
+	}
+
+	return nil
+}
+
+// DeleteTOTP will remove the secret and the recovery codes of the user.
+func (s *Store) DeleteTOTP(ctx context.Context, userId string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userId)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userId)
+
+	return checkWriteError(err)
+}
+
+// ReplaceRecoveryCodes will store new recovery codes for the user, the previous ones stop working.
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	_, err := s.db.ExecContext(ctx,
+		`WITH deleted AS (DELETE FROM recovery_codes WHERE user_id = $1)
+		INSERT INTO recovery_codes(user_id, code_hash, created_at)
+		SELECT $1, unnest($2::VARCHAR[]), $3`, userId, pq.StringArray(codeHashes), helpers.TimeNow())
+
+	return checkWriteError(err)
+}
+
+// UseRecoveryCode will mark an unused recovery code of the user as used.
+func (s *Store) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`,
+		helpers.TimeNow(), userId, codeHash)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows == 0 {
+		return ErrCodeNotUsed
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes returns how many recovery codes of the user weren't used yet.
+func (s *Store) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	var count int
+
+	err := s.db.GetContext(ctx, &count,
+		`SELECT count(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userId)
+	if err = checkWriteError(err); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// InsertMFAChallenge will add a new challenge for a login waiting for the second factor.
+func (s *Store) InsertMFAChallenge(ctx context.Context, c *model.MFAChallenge) error {
+	c.CreatedAt = helpers.TimeNow()
+
+	_, err := s.db.NamedExecContext(ctx,
+		`INSERT INTO
+		mfa_challenges(user_id, token_hash, expires_at, created_at)
+		VALUES (:user_id, :token_hash, :expires_at, :created_at)`, c)
+
+	return checkWriteError(err)
+}
+
+// TakeMFAChallengeAttempt will count an attempt to answer an unused and not expired challenge and return it.
+// Challenges which ran out of attempts are not found, the counting is done in a single statement,
+// so concurrent requests can't go over the limit.
+func (s *Store) TakeMFAChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (*model.MFAChallenge, error) {
+	var c model.MFAChallenge
+
+	err := s.db.GetContext(ctx, &c,
+		`UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2 AND attempts < $3
+		RETURNING *`, tokenHash, helpers.TimeNow(), maxAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &c, nil
+}
+
+// UseMFAChallenge will mark the challenge as used. It fails with ErrCodeNotUsed if it was already used.
+func (s *Store) UseMFAChallenge(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE mfa_challenges SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, helpers.TimeNow(), id)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrCodeNotUsed
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 encoding of the SHA1 seed "12345678901234567890" of the test vectors of RFC 6238.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The vectors of RFC 6238 appendix B have 8 digits, the codes are their last 6
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			lower, err := Code(strings.ToLower(rfcSecret), Step(time.Unix(tt.unix, 0)))
+			if err != nil || lower != tt.want {
+				t.Errorf("expected %s for the lowercase secret, got %s (%v)", tt.want, lower, err)
+			}
+		})
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Fatal("expected an error for a secret which isn't base32")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: code(current), wantStep: current, wantOK: true},
+		{name: "surrounded by spaces", code: " " + code(current) + "\n", wantStep: current, wantOK: true},
+		{name: "previous step", code: code(current - 1), wantStep: current - 1, wantOK: true},
+		{name: "next step", code: code(current + 1), wantStep: current + 1, wantOK: true},
+		{name: "two steps ago", code: code(current - 2)},
+		{name: "two steps ahead", code: code(current + 2)},
+		{name: "wrong length", code: code(current)[:Digits-1]},
+		{name: "replayed code", code: code(current), lastStep: current},
+		{name: "code of an earlier step than the used one", code: code(current - 1), lastStep: current},
+		{name: "previous step already used", code: code(current - 1), lastStep: current - 1},
+		{name: "after the previous step was used", code: code(current), lastStep: current - 1, wantStep: current, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("expected (%d, %t), got (%d, %t)", tt.wantStep, tt.wantOK, step, ok)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	if err != nil {
+		t.Fatalf("generated secret can't be used: %v", err)
+	}
+	if _, ok := Validate(secret, code, now, 0); !ok {
+		t.Error("code of the generated secret is rejected")
+	}
+}
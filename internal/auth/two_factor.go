@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/superhorsy/quest-app-backend/internal/auth/model"
+	authStore "github.com/superhorsy/quest-app-backend/internal/auth/store"
+	"github.com/superhorsy/quest-app-backend/internal/auth/totp"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+const (
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling a user who already has two-factor authentication enabled.
+	ErrTwoFactorAlreadyEnabled = errors.Error("two_factor_already_enabled: two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnrolled is returned when confirming an authenticator app before the enrollment was started.
+	ErrTwoFactorNotEnrolled = errors.Error("two_factor_not_enrolled: two-factor authentication enrollment wasn't started")
+	// ErrTwoFactorNotEnabled is returned when managing two-factor authentication of a user who hasn't enabled it.
+	ErrTwoFactorNotEnabled = errors.Error("two_factor_not_enabled: two-factor authentication isn't enabled")
+	// ErrInvalidTwoFactorCode is returned when the authenticator app code or the recovery code is wrong or already used.
+	ErrInvalidTwoFactorCode = errors.Error("invalid_two_factor_code: code is invalid")
+	// ErrInvalidMFAChallenge is returned when the login challenge is unknown, expired, used or out of attempts.
+	ErrInvalidMFAChallenge = errors.Error("invalid_mfa_challenge: login challenge is invalid, log in again")
+)
+
+const (
+	// totpIssuer is the name authenticator apps show next to the codes.
+	totpIssuer = "Questy"
+	// qrCodeSize is the width and height of the enrollment QR code in pixels.
+	qrCodeSize = 256
+	// recoveryCodeCount is how many recovery codes are handed out at once.
+	recoveryCodeCount = 10
+	// mfaChallengeTTL is how long the user has to enter the second factor after the password.
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaChallengeMaxAttempts is how many codes can be tried for a single login.
+	mfaChallengeMaxAttempts = 5
+)
+
+// EnrollTOTP will generate a new authenticator app secret for the user. Two-factor authentication is enabled
+// only after a code generated from the secret is confirmed with ConfirmTOTP.
+func (a *Auth) EnrollTOTP(ctx context.Context, userId string, account string) (*model.TOTPEnrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	if err := a.store.UpsertTOTP(ctx, userId, secret); err != nil {
+		if errors.Is(err, errors.ErrValidation) {
+			return nil, ErrTwoFactorAlreadyEnabled.Wrap(errors.ErrValidation)
+		}
+		return nil, err
+	}
+
+	uri := totp.URI(totpIssuer, account, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmTOTP will enable two-factor authentication once the code from the authenticator app is correct.
+// The recovery codes are returned only here, just their hashes are stored.
+func (a *Auth) ConfirmTOTP(ctx context.Context, userId string, code string) ([]string, error) {
+	t, err := a.store.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return nil, ErrTwoFactorNotEnrolled.Wrap(errors.ErrValidation)
+		}
+		return nil, err
+	}
+	if t.ConfirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled.Wrap(errors.ErrValidation)
+	}
+
+	step, ok := totp.Validate(*t.Secret, code, time.Now(), t.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode.Wrap(errors.ErrValidation)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.store.ConfirmTOTP(ctx, userId, step, hashes); err != nil {
+		if errors.Is(err, authStore.ErrCodeNotUsed) {
+			return nil, ErrTwoFactorAlreadyEnabled.Wrap(errors.ErrValidation)
+		}
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// GetTwoFactorStatus returns whether the user has two-factor authentication enabled.
+func (a *Auth) GetTwoFactorStatus(ctx context.Context, userId string) (*model.TwoFactorStatus, error) {
+	enabled, err := a.IsTwoFactorEnabled(ctx, userId)
+	if err != nil || !enabled {
+		return &model.TwoFactorStatus{}, err
+	}
+
+	left, err := a.store.CountRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorStatus{
+		Enabled:           true,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// IsTwoFactorEnabled checks if the user has to enter a second factor on login.
+func (a *Auth) IsTwoFactorEnabled(ctx context.Context, userId string) (bool, error) {
+	t, err := a.store.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return t.ConfirmedAt != nil, nil
+}
+
+// DisableTOTP will turn off two-factor authentication, confirmed with an authenticator app code or a recovery code.
+func (a *Auth) DisableTOTP(ctx context.Context, userId string, code string, recoveryCode string, ip string) error {
+	if err := a.confirmSecondFactor(ctx, userId, code, recoveryCode, ip); err != nil {
+		return err
+	}
+
+	return a.store.DeleteTOTP(ctx, userId)
+}
+
+// RegenerateRecoveryCodes will replace the recovery codes of the user, confirmed with an authenticator app code.
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, userId string, code string, ip string) ([]string, error) {
+	if err := a.confirmSecondFactor(ctx, userId, code, "", ip); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.store.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// CreateMFAChallenge will start the second step of the login for a user who passed the password check.
+// The returned token is exchanged for the access tokens with VerifyMFAChallenge.
+func (a *Auth) CreateMFAChallenge(ctx context.Context, userId string) (string, error) {
+	token, err := helpers.GenerateToken()
+	if err != nil {
+		return "", errors.ErrUnknown.Wrap(err)
+	}
+	tokenHash := helpers.HashToken(token)
+	expiresAt := time.Now().UTC().Add(mfaChallengeTTL)
+
+	if err := a.store.InsertMFAChallenge(ctx, &model.MFAChallenge{
+		UserID:    &userId,
+		TokenHash: &tokenHash,
+		ExpiresAt: &expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// VerifyMFAChallenge will check the second factor for the login challenge and return the id of the user on success.
+// Each challenge can be tried a limited number of times, after that the login has to start over. Wrong codes are
+// also counted per user across challenges, so the user is locked out like after wrong passwords.
+func (a *Auth) VerifyMFAChallenge(ctx context.Context, challengeToken string, code string, recoveryCode string, ip string) (string, error) {
+	if challengeToken == "" {
+		return "", ErrInvalidMFAChallenge.Wrap(errors.ErrUnauthorized)
+	}
+
+	c, err := a.store.TakeMFAChallengeAttempt(ctx, helpers.HashToken(challengeToken), mfaChallengeMaxAttempts)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return "", ErrInvalidMFAChallenge.Wrap(errors.ErrUnauthorized)
+		}
+		return "", err
+	}
+
+	if err := a.checkMFAAllowed(ctx, *c.UserID); err != nil {
+		return "", err
+	}
+
+	if err := a.checkSecondFactor(ctx, *c.UserID, code, recoveryCode); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := a.recordMFAAttempt(ctx, *c.UserID, ip, false); err != nil {
+				return "", err
+			}
+			return "", ErrInvalidTwoFactorCode.Wrap(errors.ErrUnauthorized)
+		}
+		return "", err
+	}
+
+	if err := a.store.UseMFAChallenge(ctx, *c.ID); err != nil {
+		if errors.Is(err, authStore.ErrCodeNotUsed) {
+			return "", ErrInvalidMFAChallenge.Wrap(errors.ErrUnauthorized)
+		}
+		return "", err
+	}
+
+	if err := a.recordMFAAttempt(ctx, *c.UserID, ip, true); err != nil {
+		return "", err
+	}
+
+	return *c.UserID, nil
+}
+
+// confirmSecondFactor checks the second factor of a signed in user like checkSecondFactor, counting wrong codes
+// towards the same lockout as the login, so a stolen session can't be used to guess them.
+func (a *Auth) confirmSecondFactor(ctx context.Context, userId string, code string, recoveryCode string, ip string) error {
+	if err := a.checkMFAAllowed(ctx, userId); err != nil {
+		return err
+	}
+
+	err := a.checkSecondFactor(ctx, userId, code, recoveryCode)
+	if err != nil && !errors.Is(err, ErrInvalidTwoFactorCode) {
+		return err
+	}
+	if err := a.recordMFAAttempt(ctx, userId, ip, err == nil); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// checkSecondFactor verifies the authenticator app code, or the recovery code if no code is given.
+// Accepted codes are used up.
+func (a *Auth) checkSecondFactor(ctx context.Context, userId string, code string, recoveryCode string) error {
+	t, err := a.store.GetTOTP(ctx, userId)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return ErrTwoFactorNotEnabled.Wrap(errors.ErrValidation)
+		}
+		return err
+	}
+	if t.ConfirmedAt == nil {
+		return ErrTwoFactorNotEnabled.Wrap(errors.ErrValidation)
+	}
+
+	if code == "" && recoveryCode != "" {
+		err = a.store.UseRecoveryCode(ctx, userId, hashRecoveryCode(recoveryCode))
+	} else {
+		step, ok := totp.Validate(*t.Secret, code, time.Now(), t.LastUsedStep)
+		if !ok {
+			return ErrInvalidTwoFactorCode.Wrap(errors.ErrValidation)
+		}
+		err = a.store.UseTOTPStep(ctx, userId, step)
+	}
+	if errors.Is(err, authStore.ErrCodeNotUsed) {
+		return ErrInvalidTwoFactorCode.Wrap(errors.ErrValidation)
+	}
+
+	return err
+}
+
+// generateRecoveryCodes returns new recovery codes formatted like "abcd-efgh" together with their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, errors.ErrUnknown.Wrap(err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))
+		code = code[:4] + "-" + code[4:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores the case and the separators, so codes typed by hand are still accepted.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return helpers.HashToken(code)
+}
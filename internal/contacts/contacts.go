@@ -0,0 +1,90 @@
+package contacts
+
+import (
+	"context"
+	"strings"
+
+	"github.com/superhorsy/quest-app-backend/internal/contacts/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+)
+
+// ErrEmptyEmail is returned when a contact is saved without an email.
+const ErrEmptyEmail = errors.Error("empty_email: email is empty")
+
+// Store represents a type for storing contacts in a database.
+type Store interface {
+	InsertContact(ctx context.Context, c *model.Contact) (*model.Contact, error)
+	SaveRecipient(ctx context.Context, ownerId string, email string, name string) error
+	GetContact(ctx context.Context, ownerId string, id string) (*model.Contact, error)
+	GetContacts(ctx context.Context, ownerId string, search string, offset int64, limit int64) ([]model.Contact, error)
+	UpdateContact(ctx context.Context, c *model.Contact) (*model.Contact, error)
+	DeleteContact(ctx context.Context, ownerId string, id string) error
+}
+
+// Contacts provides functionality for managing the address book quests are sent from.
+type Contacts struct {
+	store Store
+}
+
+// New will instantiate a new instance of Contacts.
+func New(s Store) *Contacts {
+	return &Contacts{
+		store: s,
+	}
+}
+
+// CreateContact adds a contact to the address book of the user.
+func (c *Contacts) CreateContact(ctx context.Context, userId string, contact *model.Contact) (*model.Contact, error) {
+	if err := normalize(contact); err != nil {
+		return nil, err
+	}
+	contact.Owner = &userId
+
+	return c.store.InsertContact(ctx, contact)
+}
+
+// GetContact returns a contact from the address book of the user.
+func (c *Contacts) GetContact(ctx context.Context, userId string, id string) (*model.Contact, error) {
+	return c.store.GetContact(ctx, userId, id)
+}
+
+// GetContacts returns the contacts of the user whose name or email contains the search, for autocompletion.
+func (c *Contacts) GetContacts(ctx context.Context, userId string, search string, offset int64, limit int64) ([]model.Contact, error) {
+	return c.store.GetContacts(ctx, userId, strings.TrimSpace(search), offset, limit)
+}
+
+// UpdateContact changes the email and the name of a contact of the user.
+func (c *Contacts) UpdateContact(ctx context.Context, userId string, contact *model.Contact) (*model.Contact, error) {
+	if err := normalize(contact); err != nil {
+		return nil, err
+	}
+	contact.Owner = &userId
+
+	return c.store.UpdateContact(ctx, contact)
+}
+
+// DeleteContact removes a contact from the address book of the user, quests already sent to it are kept.
+func (c *Contacts) DeleteContact(ctx context.Context, userId string, id string) error {
+	return c.store.DeleteContact(ctx, userId, id)
+}
+
+// SaveRecipient adds the recipient of a sent quest to the address book of the user, unless the email is already there.
+func (c *Contacts) SaveRecipient(ctx context.Context, userId string, email string, name string) error {
+	return c.store.SaveRecipient(ctx, userId, email, name)
+}
+
+func normalize(contact *model.Contact) error {
+	if contact.Email == nil || strings.TrimSpace(*contact.Email) == "" {
+		return ErrEmptyEmail.Wrap(errors.ErrValidation)
+	}
+	email := strings.TrimSpace(*contact.Email)
+	contact.Email = &email
+
+	name := ""
+	if contact.Name != nil {
+		name = strings.TrimSpace(*contact.Name)
+	}
+	contact.Name = &name
+
+	return nil
+}
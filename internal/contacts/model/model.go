@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Contact represents a recipient saved in the address book of a user.
+type Contact struct {
+	ID    *string `json:"id" db:"id"`
+	Owner *string `json:"-" db:"owner"`
+	Email *string `json:"email" db:"email"`
+	Name  *string `json:"name" db:"name"`
+
+	CreatedAt *time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+}
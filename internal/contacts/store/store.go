@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/superhorsy/quest-app-backend/internal/contacts/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+)
+
+const (
+	// ErrContactAlreadyExists is returned when the address book already has a contact with the email.
+	ErrContactAlreadyExists = errors.Error("contact_already_exists: contact with this email already exists")
+	// ErrInvalidEmail is returned when the email is empty.
+	ErrInvalidEmail = errors.Error("invalid_email: email is invalid")
+	// ErrInvalidID is returned when the ID is not a valid UUID or is empty.
+	ErrInvalidID = errors.Error("invalid_id: id is invalid")
+)
+
+// DB represents a type for interfacing with a database.
+type DB interface {
+	NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Store provides functionality for working with a database.
+type Store struct {
+	db DB
+}
+
+// New will instantiate a new instance of Store.
+func New(db DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// InsertContact will add a new contact to the address book of its owner.
+func (s *Store) InsertContact(ctx context.Context, c *model.Contact) (*model.Contact, error) {
+	c.CreatedAt = helpers.TimeNow()
+	c.UpdatedAt = c.CreatedAt
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`INSERT INTO 
+		contacts("owner", email, "name", created_at, updated_at) 
+		VALUES (:owner, :email, :name, :created_at, :updated_at) 
+		RETURNING *`, c)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrUnknown
+	}
+
+	createdContact := &model.Contact{}
+
+	if err := res.StructScan(createdContact); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return createdContact, nil
+}
+
+// SaveRecipient will add the email to the address book of the owner, unless it is already there.
+// The name of an existing contact is kept, as the user might have edited it.
+func (s *Store) SaveRecipient(ctx context.Context, ownerId string, email string, name string) error {
+	now := helpers.TimeNow()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO contacts("owner", email, "name", created_at, updated_at) VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT ("owner", email) DO NOTHING`, ownerId, email, name, now)
+
+	return checkWriteError(err)
+}
+
+// GetContact will retrieve a contact of the owner via its ID.
+func (s *Store) GetContact(ctx context.Context, ownerId string, id string) (*model.Contact, error) {
+	var c model.Contact
+
+	if err := s.db.GetContext(ctx, &c, `SELECT * FROM contacts WHERE id = $1 AND "owner" = $2`, id, ownerId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &c, nil
+}
+
+// GetContacts returns the contacts of the owner ordered by name. The search matches anywhere in the name or the email,
+// an empty one matches every contact.
+func (s *Store) GetContacts(ctx context.Context, ownerId string, search string, offset int64, limit int64) ([]model.Contact, error) {
+	contacts := []model.Contact{}
+
+	pattern := "%" + escapeLike(search) + "%"
+	err := s.db.SelectContext(ctx, &contacts,
+		`SELECT * FROM contacts WHERE "owner" = $1 AND ("name" ILIKE $2 OR email ILIKE $2)
+		ORDER BY lower("name"), email LIMIT $3 OFFSET $4`, ownerId, pattern, limit, offset)
+	if err != nil {
+		return nil, checkWriteError(err)
+	}
+
+	return contacts, nil
+}
+
+// UpdateContact will update the email and the name of a contact of its owner.
+func (s *Store) UpdateContact(ctx context.Context, c *model.Contact) (*model.Contact, error) {
+	c.UpdatedAt = helpers.TimeNow()
+
+	res, err := s.db.NamedQueryContext(ctx,
+		`UPDATE contacts SET email = :email, "name" = :name, updated_at = :updated_at 
+		WHERE id = :id AND "owner" = :owner RETURNING *`, c)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	if !res.Next() {
+		return nil, errors.ErrNotFound
+	}
+
+	updatedContact := &model.Contact{}
+
+	if err := res.StructScan(updatedContact); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return updatedContact, nil
+}
+
+// DeleteContact will delete a contact of the owner.
+func (s *Store) DeleteContact(ctx context.Context, ownerId string, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM contacts WHERE id = $1 AND "owner" = $2`, id, ownerId)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// escapeLike escapes the wildcards of LIKE patterns, so they match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func checkWriteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "string_data_right_truncation":
+			return errors.ErrValidation.Wrap(err)
+		case "check_violation", "not_null_violation":
+			if strings.Contains(pqErr.Error(), "email") {
+				return ErrInvalidEmail.Wrap(errors.ErrValidation.Wrap(err))
+			}
+			return errors.ErrValidation.Wrap(err)
+		case "unique_violation":
+			if strings.Contains(pqErr.Error(), "contacts_owner_email_unique") {
+				return ErrContactAlreadyExists.Wrap(errors.ErrValidation.Wrap(err))
+			}
+			return errors.ErrValidation.Wrap(err)
+		case "invalid_text_representation":
+			if strings.Contains(pqErr.Error(), "uuid") {
+				return ErrInvalidID.Wrap(errors.ErrValidation.Wrap(err))
+			}
+		}
+	}
+
+	return errors.ErrUnknown.Wrap(err)
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"os"
+	"time"
+
 	"github.com/superhorsy/quest-app-backend/internal/core/drivers/psql"
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
 	"github.com/superhorsy/quest-app-backend/internal/core/listeners/http"
@@ -10,11 +13,92 @@ var config *AppConfig = nil
 
 // AppConfig represents the configuration of our application.
 type AppConfig struct {
-	HTTP           http.Config `yaml:"http"`
-	PSQL           psql.Config `yaml:"psql"`
-	PurgeOnRestart bool        `yaml:"purge_on_restart"`
-	JwtPrivateKey  string      `env:"JWT_PRIVATE_KEY" validate:"required"`
-	SentryDSN      string      `env:"SENTRY_DSN"`
+	HTTP           http.Config          `yaml:"http"`
+	PSQL           psql.Config          `yaml:"psql"`
+	Auth           AuthConfig           `yaml:"auth"`
+	JWT            JWTConfig            `yaml:"jwt"`
+	Password       PasswordConfig       `yaml:"password"`
+	Trash          TrashConfig          `yaml:"trash"`
+	OIDC           []OIDCProviderConfig `yaml:"oidc" validate:"dive"`
+	PurgeOnRestart bool                 `yaml:"purge_on_restart"`
+	AppURL         string               `yaml:"app_url" env:"APP_URL" validate:"required"`
+	// TrustedProxies are the IPs or CIDRs of the proxies in front of the app, X-Forwarded-For is only read
+	// from requests they make.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" envSeparator:"," validate:"dive,cidr|ip"`
+	// AdminEmails are promoted to admins on startup once their accounts are verified, so the first admin
+	// doesn't have to be set up in the database.
+	AdminEmails   []string `yaml:"admin_emails" env:"ADMIN_EMAILS" envSeparator:","`
+	JwtPrivateKey string   `env:"JWT_PRIVATE_KEY"`
+	SentryDSN     string   `env:"SENTRY_DSN"`
+}
+
+// AuthConfig represents the lifetime of the tokens issued to users and guests and the limits of failed logins.
+type AuthConfig struct {
+	AccessTokenTTL     time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL" validate:"required"`
+	RefreshTokenTTL    time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" validate:"required"`
+	PasswordResetTTL   time.Duration `yaml:"password_reset_ttl" env:"PASSWORD_RESET_TTL" validate:"required"`
+	EmailVerifyTTL     time.Duration `yaml:"email_verify_ttl" env:"EMAIL_VERIFY_TTL" validate:"required"`
+	LoginMaxFailures   int           `yaml:"login_max_failures" env:"LOGIN_MAX_FAILURES" validate:"required"`
+	LoginMaxIPFailures int           `yaml:"login_max_ip_failures" env:"LOGIN_MAX_IP_FAILURES" validate:"required"`
+	LoginLockout       time.Duration `yaml:"login_lockout" env:"LOGIN_LOCKOUT" validate:"required"`
+	LoginMaxLockout    time.Duration `yaml:"login_max_lockout" env:"LOGIN_MAX_LOCKOUT" validate:"required"`
+	GuestLinkTTL       time.Duration `yaml:"guest_link_ttl" env:"GUEST_LINK_TTL" validate:"required"`
+}
+
+// PasswordConfig represents the rules new passwords have to follow and how passwords are hashed.
+// Changing the algorithm or its parameters rehashes passwords on the next login.
+type PasswordConfig struct {
+	MinLength        int    `yaml:"min_length" env:"PASSWORD_MIN_LENGTH" validate:"min=1"`
+	BreachedListFile string `yaml:"breached_list_file" env:"PASSWORD_BREACHED_LIST_FILE"`
+	Algorithm        string `yaml:"algorithm" env:"PASSWORD_ALGORITHM" validate:"oneof=bcrypt argon2id"`
+	BcryptCost       int    `yaml:"bcrypt_cost" env:"PASSWORD_BCRYPT_COST"`
+	Argon2Time       uint32 `yaml:"argon2_time" env:"PASSWORD_ARGON2_TIME"`
+	Argon2Memory     uint32 `yaml:"argon2_memory" env:"PASSWORD_ARGON2_MEMORY"`
+	Argon2Threads    uint8  `yaml:"argon2_threads" env:"PASSWORD_ARGON2_THREADS"`
+}
+
+// TrashConfig represents how long deleted quests and steps are kept before they are purged and how often
+// the trash is checked.
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" env:"TRASH_RETENTION" validate:"required"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"TRASH_PURGE_INTERVAL" validate:"required"`
+}
+
+// JWTConfig represents the keys tokens are signed and verified with. Tokens are signed with the key named by SigningKey,
+// the other keys are only used to verify tokens signed before a rotation. Without a signing key tokens are signed
+// with JwtPrivateKey using HS256.
+type JWTConfig struct {
+	SigningKey string         `yaml:"signing_key" env:"JWT_SIGNING_KEY"`
+	Keys       []JWTKeyConfig `yaml:"keys" validate:"dive"`
+}
+
+// JWTKeyConfig represents a single key, published with its id in the JWKS.
+// Keys only verifying tokens of other services or retired keys can be configured with just the public key.
+type JWTKeyConfig struct {
+	ID             string `yaml:"id" validate:"required"`
+	Algorithm      string `yaml:"algorithm" validate:"required,oneof=RS256 EdDSA"`
+	PrivateKeyFile string `yaml:"private_key_file" validate:"required_without=PublicKeyFile"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+}
+
+// OIDCProviderConfig represents an OpenID Connect identity provider users can sign in with.
+type OIDCProviderConfig struct {
+	Name        string   `yaml:"name" validate:"required"`
+	Issuer      string   `yaml:"issuer" validate:"required,url"`
+	ClientID    string   `yaml:"client_id" validate:"required"`
+	RedirectURL string   `yaml:"redirect_url" validate:"required,url"`
+	Scopes      []string `yaml:"scopes"`
+	// ClientSecretEnv is the name of the environment variable holding the client secret,
+	// so secrets don't end up in config files.
+	ClientSecretEnv string `yaml:"client_secret_env"`
+}
+
+// ClientSecret returns the client secret of the provider, empty for public clients.
+func (c OIDCProviderConfig) ClientSecret() string {
+	if c.ClientSecretEnv == "" {
+		return ""
+	}
+	return os.Getenv(c.ClientSecretEnv)
 }
 
 func (*AppConfig) Set(appConfig AppConfig) {
@@ -15,8 +15,10 @@ const (
 	// ErrValidation is returned when the parameters don't pass validation.
 	ErrValidation = Error("err_validation: failed validation")
 	// ErrNotFound is returned when the requested resource is not found.
-	ErrNotFound  = Error("Не найдено")
-	ErrForbidden = Error("Ошибка доступа")
+	ErrNotFound        = Error("Не найдено")
+	ErrForbidden       = Error("Ошибка доступа")
+	ErrUnauthorized    = Error("Требуется авторизация")
+	ErrTooManyRequests = Error("Слишком много запросов")
 )
 
 // ErrSeperator is used to determine the boundaries of the errors in the hierarchy.
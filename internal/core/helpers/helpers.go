@@ -2,53 +2,97 @@ package helpers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/superhorsy/quest-app-backend/internal/core/config"
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
-	"golang.org/x/crypto/bcrypt"
-	"os"
+	"github.com/superhorsy/quest-app-backend/internal/core/keyset"
 	"strings"
 	"time"
 )
 
-func HandleError(err error) {
-	if err != nil {
-		panic(err.Error())
-	}
-}
+// signingKeys signs and verifies every JWT issued by the app, it is loaded from the configuration on start.
+var signingKeys *keyset.KeySet
 
-func HashAndSalt(pass []byte) string {
-	hashed, err := bcrypt.GenerateFromPassword(pass, bcrypt.MinCost)
-	HandleError(err)
-	return string(hashed)
+// SetSigningKeys sets the keys CreateJwtToken, CreateGuestToken and ParseToken use.
+func SetSigningKeys(ks *keyset.KeySet) {
+	signingKeys = ks
 }
 
-func CreateJwtToken(id string) (*string, error) {
-	tokenContent := jwt.MapClaims{
-		"sub": id,
-		//"exp": time.Now().Add(time.Minute * 60).Unix(),
-	}
-	jwtToken := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), tokenContent)
-	token, err := jwtToken.SignedString([]byte(os.Getenv("JWT_PRIVATE_KEY")))
+// CreateJwtToken issues an access token for the user which expires after ttl.
+// The session id is put into the jti claim, so the token can be revoked together with its session.
+func CreateJwtToken(id string, sessionId string, role string, ttl time.Duration) (*string, error) {
+	now := time.Now()
+	token, err := signingKeys.Sign(jwt.MapClaims{
+		"sub":  id,
+		"jti":  sessionId,
+		"role": role,
+		"iat":  now.Unix(),
+		"exp":  now.Add(ttl).Unix(),
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &token, nil
 }
 
-func ParseToken(authHeader string) (jwt.MapClaims, error) {
-	cleanJWT := strings.Replace(authHeader, "Bearer ", "", -1)
-	claims := jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(cleanJWT, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_PRIVATE_KEY")), nil
+// guestTokenType tells magic link tokens apart from access tokens signed with the same key.
+const guestTokenType = "guest"
+
+// CreateGuestToken issues a magic link token which lets a guest play the quest sent to the email without an account.
+func CreateGuestToken(questId string, email string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	return signingKeys.Sign(jwt.MapClaims{
+		"sub":   email,
+		"quest": questId,
+		"typ":   guestTokenType,
+		"iat":   now.Unix(),
+		"exp":   now.Add(ttl).Unix(),
 	})
+}
+
+// ParseGuestToken verifies a magic link token and returns the quest and the email it was issued for.
+func ParseGuestToken(guestToken string) (string, string, error) {
+	claims, err := ParseToken(guestToken)
 	if err != nil {
-		return nil, err
+		return "", "", err
+	}
+
+	questId, _ := claims["quest"].(string)
+	email, _ := claims["sub"].(string)
+	if claims["typ"] != guestTokenType || questId == "" || email == "" {
+		return "", "", errors.New("Token is invalid")
 	}
-	if !token.Valid {
-		return nil, errors.New("Token is invalid")
+
+	return questId, email, nil
+}
+
+// JWKS returns the public keys tokens are verified with.
+func JWKS() keyset.JWKS {
+	return signingKeys.JWKS()
+}
+
+// ParseToken verifies the signature and the expiration time of the token from the Authorization header.
+func ParseToken(authHeader string) (jwt.MapClaims, error) {
+	return signingKeys.Parse(strings.Replace(authHeader, "Bearer ", "", -1))
+}
+
+// GenerateToken returns a random url safe token which can be handed out to users.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return claims, nil
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hash of a token generated with GenerateToken. Only hashes are stored in the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func TimeNow() *time.Time {
@@ -0,0 +1,227 @@
+// Package keyset signs and verifies the JWTs issued by the app and publishes their verification keys as a JWKS.
+package keyset
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/superhorsy/quest-app-backend/internal/core/config"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+)
+
+const (
+	// ErrNoSigningKey is returned when neither a signing key nor the legacy secret is configured.
+	ErrNoSigningKey = errors.Error("no JWT signing key configured, set jwt.signing_key or JWT_PRIVATE_KEY")
+	// ErrUnknownKey is returned when a token is signed with a key which isn't configured.
+	ErrUnknownKey = errors.Error("token is signed with an unknown key")
+)
+
+// minRSAKeyBits is the smallest RSA key accepted, see RFC 7518 section 3.3.
+const minRSAKeyBits = 2048
+
+// Key represents a single configured key.
+type Key struct {
+	ID        string
+	Algorithm string
+	private   interface{}
+	public    interface{}
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are verified with.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	// legacySecret verifies tokens signed with HS256 before asymmetric keys were configured, and signs tokens
+	// if no signing key is set.
+	legacySecret []byte
+}
+
+// New will load the keys from the configuration.
+func New(cfg config.JWTConfig, legacySecret string) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]*Key{}}
+	if legacySecret != "" {
+		ks.legacySecret = []byte(legacySecret)
+	}
+
+	for _, c := range cfg.Keys {
+		if _, ok := ks.keys[c.ID]; ok {
+			return nil, fmt.Errorf("duplicate JWT key %q", c.ID)
+		}
+		key, err := loadKey(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %q: %w", c.ID, err)
+		}
+		ks.keys[c.ID] = key
+	}
+
+	if cfg.SigningKey != "" {
+		key, ok := ks.keys[cfg.SigningKey]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("JWT signing key %q has no private key configured", cfg.SigningKey)
+		}
+		ks.signing = key
+	} else if ks.legacySecret == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return ks, nil
+}
+
+// Sign returns the signed token with the claims, its header names the key in kid.
+func (ks *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.legacySecret)
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(ks.signing.Algorithm), claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.private)
+}
+
+// Parse verifies the signature and the expiration time of the token and reads its claims.
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, ks.verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("Token is invalid")
+	}
+	return claims, nil
+}
+
+// verificationKey picks the key by the kid header, checking that the token is signed with the algorithm of the key,
+// so a public key can't be used as an HMAC secret.
+func (ks *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || ks.legacySecret == nil {
+			return nil, errors.New("Unexpected signing method")
+		}
+		return ks.legacySecret, nil
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("Unexpected signing method")
+	}
+	return key.public, nil
+}
+
+// JWK represents a single public key of a JSON Web Key Set, see RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS represents the verification keys published for other services.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set ordered by id. The legacy secret is never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		k := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			k.Kty = "RSA"
+			k.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			k.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			k.Kty = "OKP"
+			k.Crv = "Ed25519"
+			k.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, k)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+func loadKey(c config.JWTKeyConfig) (*Key, error) {
+	key := &Key{ID: c.ID, Algorithm: c.Algorithm}
+
+	if c.PrivateKeyFile != "" {
+		data, err := os.ReadFile(c.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		switch c.Algorithm {
+		case jwt.SigningMethodRS256.Alg():
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, err
+			}
+			key.private, key.public = private, &private.PublicKey
+		case SigningMethodEdDSA.Alg():
+			private, err := parsePEM(data, x509.ParsePKCS8PrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			ed, ok := private.(ed25519.PrivateKey)
+			if !ok {
+				return nil, errors.New("key is not an Ed25519 private key")
+			}
+			key.private, key.public = ed, ed.Public()
+		}
+	} else {
+		data, err := os.ReadFile(c.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key.public, err = parsePEM(data, x509.ParsePKIXPublicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		if c.Algorithm != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("RSA key can't be used with %s", c.Algorithm)
+		}
+		if public.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+	case ed25519.PublicKey:
+		if c.Algorithm != SigningMethodEdDSA.Alg() {
+			return nil, fmt.Errorf("Ed25519 key can't be used with %s", c.Algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	return key, nil
+}
+
+func parsePEM(data []byte, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+	return parse(block.Bytes)
+}
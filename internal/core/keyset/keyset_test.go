@@ -0,0 +1,311 @@
+package keyset
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/superhorsy/quest-app-backend/internal/core/config"
+)
+
+const legacySecret = "legacy-secret"
+
+type testKeys struct {
+	rsa        *rsa.PrivateKey
+	rsaFile    string
+	rsaPubFile string
+	rsaSmall   string
+	edFile     string
+	edPubFile  string
+}
+
+func writePEM(t *testing.T, name string, blockType string, der []byte) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(ed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPubDER, err := x509.MarshalPKIXPublicKey(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testKeys{
+		rsa:        private,
+		rsaFile:    writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private)),
+		rsaPubFile: writePEM(t, "rsa.pub.pem", "PUBLIC KEY", public),
+		rsaSmall:   writePEM(t, "small.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(small)),
+		edFile:     writePEM(t, "ed.pem", "PRIVATE KEY", edDER),
+		edPubFile:  writePEM(t, "ed.pub.pem", "PUBLIC KEY", edPubDER),
+	}
+}
+
+func TestNew(t *testing.T) {
+	keys := newTestKeys(t)
+
+	tests := []struct {
+		name    string
+		cfg     config.JWTConfig
+		legacy  string
+		wantErr string
+	}{
+		{
+			name: "rsa and ed25519 keys",
+			cfg: config.JWTConfig{SigningKey: "rsa", Keys: []config.JWTKeyConfig{
+				{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: keys.rsaFile},
+				{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: keys.edFile},
+			}},
+		},
+		{
+			name:   "legacy secret only",
+			legacy: legacySecret,
+		},
+		{
+			name:    "nothing to sign with",
+			wantErr: ErrNoSigningKey.Error(),
+		},
+		{
+			name: "rsa key with EdDSA",
+			cfg: config.JWTConfig{SigningKey: "rsa", Keys: []config.JWTKeyConfig{
+				{ID: "rsa", Algorithm: "EdDSA", PublicKeyFile: keys.rsaPubFile},
+			}},
+			wantErr: "RSA key can't be used with EdDSA",
+		},
+		{
+			name: "ed25519 key with RS256",
+			cfg: config.JWTConfig{Keys: []config.JWTKeyConfig{
+				{ID: "ed", Algorithm: "RS256", PublicKeyFile: keys.edPubFile},
+			}},
+			legacy:  legacySecret,
+			wantErr: "Ed25519 key can't be used with RS256",
+		},
+		{
+			name: "rsa key below the minimum size",
+			cfg: config.JWTConfig{SigningKey: "rsa", Keys: []config.JWTKeyConfig{
+				{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: keys.rsaSmall},
+			}},
+			wantErr: "RSA key must be at least 2048 bits",
+		},
+		{
+			name: "duplicate key id",
+			cfg: config.JWTConfig{SigningKey: "rsa", Keys: []config.JWTKeyConfig{
+				{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: keys.rsaFile},
+				{ID: "rsa", Algorithm: "EdDSA", PrivateKeyFile: keys.edFile},
+			}},
+			wantErr: `duplicate JWT key "rsa"`,
+		},
+		{
+			name: "signing key without private key",
+			cfg: config.JWTConfig{SigningKey: "rsa", Keys: []config.JWTKeyConfig{
+				{ID: "rsa", Algorithm: "RS256", PublicKeyFile: keys.rsaPubFile},
+			}},
+			wantErr: "has no private key configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, tt.legacy)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error to contain %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	keys := newTestKeys(t)
+
+	ks, err := New(config.JWTConfig{SigningKey: "rsa", Keys: []config.JWTKeyConfig{
+		{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: keys.rsaFile},
+		{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: keys.edFile},
+	}}, legacySecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM, err := os.ReadFile(keys.rsaPubFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		raw, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{
+			name: "signed by the set",
+			token: func() string {
+				raw, err := ks.Sign(jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+		},
+		{
+			name: "legacy HS256 without kid",
+			token: func() string {
+				return sign(jwt.SigningMethodHS256, "", []byte(legacySecret))
+			},
+		},
+		{
+			name: "HS256 with the kid of the rsa key",
+			token: func() string {
+				return sign(jwt.SigningMethodHS256, "rsa", rsaPEM)
+			},
+			wantErr: "Unexpected signing method",
+		},
+		{
+			name: "HS256 with the legacy secret and a kid",
+			token: func() string {
+				return sign(jwt.SigningMethodHS256, "rsa", []byte(legacySecret))
+			},
+			wantErr: "Unexpected signing method",
+		},
+		{
+			name: "RS256 with the kid of the ed25519 key",
+			token: func() string {
+				return sign(jwt.SigningMethodRS256, "ed", keys.rsa)
+			},
+			wantErr: "Unexpected signing method",
+		},
+		{
+			name: "RS256 without kid",
+			token: func() string {
+				return sign(jwt.SigningMethodRS256, "", keys.rsa)
+			},
+			wantErr: "Unexpected signing method",
+		},
+		{
+			name: "unknown kid",
+			token: func() string {
+				return sign(jwt.SigningMethodRS256, "retired", keys.rsa)
+			},
+			wantErr: ErrUnknownKey.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ks.Parse(tt.token())
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if claims["sub"] != "user-1" {
+					t.Errorf("unexpected claims: %v", claims)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error to contain %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseWithoutLegacySecret(t *testing.T) {
+	keys := newTestKeys(t)
+
+	ks, err := New(config.JWTConfig{SigningKey: "ed", Keys: []config.JWTKeyConfig{
+		{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: keys.edFile},
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-1"}).SignedString([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(raw); err == nil {
+		t.Fatal("expected HS256 token to be rejected without a legacy secret")
+	}
+
+	signed, err := ks.Sign(jwt.MapClaims{"sub": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Parse(signed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+
+	ks, err := New(config.JWTConfig{SigningKey: "rsa", Keys: []config.JWTKeyConfig{
+		{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: keys.rsaFile},
+		{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: keys.edFile},
+	}}, legacySecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := ks.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(set.Keys))
+	}
+	if k := set.Keys[0]; k.Kid != "ed" || k.Kty != "OKP" || k.Alg != "EdDSA" || k.X == "" {
+		t.Errorf("unexpected ed25519 key: %+v", k)
+	}
+	if k := set.Keys[1]; k.Kid != "rsa" || k.Kty != "RSA" || k.Alg != "RS256" || k.N == "" || k.E == "" {
+		t.Errorf("unexpected rsa key: %+v", k)
+	}
+}
@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"go.uber.org/zap"
+)
+
+// Job represents work run periodically by the scheduler.
+type Job func(ctx context.Context) error
+
+// Scheduler runs a job right after start and then every interval, alongside the other listeners.
+type Scheduler struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// New instantiates a new instance of Scheduler.
+func New(name string, interval time.Duration, job Job) *Scheduler {
+	return &Scheduler{
+		name:     name,
+		interval: interval,
+		job:      job,
+	}
+}
+
+// Listen runs the job until the context is done. Failures are logged and the job is retried on the next tick.
+func (s *Scheduler) Listen(ctx context.Context) error {
+	logging.From(ctx).Info(fmt.Sprintf("scheduler %s starting, runs every %s", s.name, s.interval))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.job(ctx); err != nil {
+			logging.From(ctx).Error("scheduled job failed", zap.String("job", s.name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			logging.From(ctx).Info(fmt.Sprintf("scheduler %s stopped", s.name))
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
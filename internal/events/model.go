@@ -21,6 +21,8 @@ const (
 	EventTypeQuestUpdated EventType = "quest_updated"
 	EventTypeQuestDeleted EventType = "quest_deleted"
 	EventTypeQuestSent    EventType = "quest_sent"
+	// EventTypeQuestTrashed is triggered when a quest is moved to the trash, quest_deleted follows once it is purged.
+	EventTypeQuestTrashed EventType = "quest_trashed"
 )
 
 // UserEvent represents an event that occurs on a user entity.
@@ -34,6 +36,8 @@ type QuestEvent struct {
 	EventType EventType                  `json:"event_type"`
 	ID        string                     `json:"id"`
 	Quest     *questModel.QuestWithSteps `json:"quest"`
+	// SourceID is the quest a cloned quest was copied from
+	SourceID string `json:"source_id,omitempty"`
 }
 
 type MediaEvent struct {
@@ -3,11 +3,12 @@ package file_storage
 import (
 	"context"
 	"io"
-	"mime/multipart"
 	"os"
 	"path/filepath"
 )
 
+const filesDir = "files"
+
 type LocalFileStorage struct {
 }
 
@@ -16,8 +17,8 @@ func New() *LocalFileStorage {
 	return &LocalFileStorage{}
 }
 
-func (s *LocalFileStorage) Upload(_ context.Context, file multipart.File, filename string) error {
-	path := filepath.Join(".", "files")
+func (s *LocalFileStorage) Upload(_ context.Context, file io.Reader, filename string) error {
+	path := filepath.Join(".", filesDir)
 	_ = os.MkdirAll(path, os.ModePerm)
 
 	fullPath := path + "/" + filename
@@ -35,3 +36,28 @@ func (s *LocalFileStorage) Upload(_ context.Context, file multipart.File, filena
 	}
 	return nil
 }
+
+// Open opens the stored file for reading.
+func (s *LocalFileStorage) Open(_ context.Context, filename string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(".", filesDir, filepath.Base(filename)))
+}
+
+// Delete removes the stored file, a missing file is not an error.
+func (s *LocalFileStorage) Delete(_ context.Context, filename string) error {
+	err := os.Remove(filepath.Join(".", filesDir, filepath.Base(filename)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Copy stores a copy of the stored file under another name.
+func (s *LocalFileStorage) Copy(ctx context.Context, filename string, copyFilename string) error {
+	src, err := os.Open(filepath.Join(".", filesDir, filepath.Base(filename)))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return s.Upload(ctx, src, filepath.Base(copyFilename))
+}
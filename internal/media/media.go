@@ -3,13 +3,19 @@ package media
 import (
 	"context"
 	"fmt"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
 	"github.com/superhorsy/quest-app-backend/internal/events"
 	fileStorage "github.com/superhorsy/quest-app-backend/internal/media/file_storage"
 	"github.com/superhorsy/quest-app-backend/internal/media/model"
 	"github.com/superhorsy/quest-app-backend/internal/media/store"
-	"mime/multipart"
+	"github.com/superhorsy/quest-app-backend/internal/transport/http"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+
+	"go.uber.org/zap"
 )
 
 // Events represents a type for producing events on user CRUD operations.
@@ -31,8 +37,11 @@ func New(rs *store.RecordStore, fs *fileStorage.LocalFileStorage, e Events) *Med
 	}
 }
 
-func (m Media) UploadFile(ctx context.Context, file multipart.File, filename string, mediaType model.MediaType) (*model.MediaRecord, error) {
+func (m Media) UploadFile(ctx context.Context, file io.Reader, filename string, mediaType model.MediaType) (*model.MediaRecord, error) {
+	userId := ctx.Value(http.ContextUserIdKey).(string)
+
 	record := &model.MediaRecord{
+		Owner:   &userId,
 		Storage: "local",
 		Type:    mediaType,
 	}
@@ -43,19 +52,54 @@ func (m Media) UploadFile(ctx context.Context, file multipart.File, filename str
 	}
 
 	ext := filepath.Ext(filename)
-	filename = fmt.Sprintf("%s%s", record.ID, ext)
+	record.Filename = fmt.Sprintf("%s%s", record.ID, ext)
+	record.Link = link(record.Filename, mediaType)
 
-	err = m.fileStorage.Upload(ctx, file, filename)
+	if err := m.fileStorage.Upload(ctx, file, record.Filename); err != nil {
+		m.discard(ctx, record)
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
 
-	record.Filename = filename
+	record, err = m.saveFile(ctx, record)
+	if err != nil {
+		return nil, err
+	}
 
-	staticFilesEndpoint := os.Getenv("STATIC_FILES_ENDPOINT")
-	if staticFilesEndpoint == "" || mediaType == model.Sound {
-		staticFilesEndpoint = "/files/"
+	m.events.Produce(ctx, events.TopicMedia, events.MediaEvent{
+		EventType:   events.EventTypeUserCreated,
+		ID:          record.ID,
+		MediaRecord: record,
+	})
+
+	return record, nil
+}
+
+// CopyMedia duplicates the media record and its file for the current user, so the copy lives on when the original
+// is deleted.
+func (m Media) CopyMedia(ctx context.Context, source *model.MediaRecord) (*model.MediaRecord, error) {
+	userId := ctx.Value(http.ContextUserIdKey).(string)
+
+	record, err := m.recordStore.InsertMedia(ctx, &model.MediaRecord{
+		Owner:   &userId,
+		Storage: source.Storage,
+		Type:    source.Type,
+	})
+	if err != nil {
+		return nil, err
 	}
-	record.Link = fmt.Sprintf("%s%s", staticFilesEndpoint, filename)
 
-	record, err = m.recordStore.UpdateMedia(ctx, record)
+	record.Filename = fmt.Sprintf("%s%s", record.ID, filepath.Ext(source.Filename))
+	record.Link = link(record.Filename, record.Type)
+
+	if err := m.fileStorage.Copy(ctx, source.Filename, record.Filename); err != nil {
+		m.discard(ctx, record)
+		if os.IsNotExist(err) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	record, err = m.saveFile(ctx, record)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +113,28 @@ func (m Media) UploadFile(ctx context.Context, file multipart.File, filename str
 	return record, nil
 }
 
+// DeleteMedia removes the media record and its stored file.
+func (m Media) DeleteMedia(ctx context.Context, record *model.MediaRecord) error {
+	if err := m.recordStore.DeleteMedia(ctx, record.ID); err != nil {
+		return err
+	}
+	if err := m.fileStorage.Delete(ctx, record.Filename); err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+
+	m.events.Produce(ctx, events.TopicMedia, events.MediaEvent{
+		EventType: events.EventTypeUserDeleted,
+		ID:        record.ID,
+	})
+
+	return nil
+}
+
+// GetMediaByLink returns the media a link in the content of a step points to.
+func (m Media) GetMediaByLink(ctx context.Context, link string) (*model.MediaRecord, error) {
+	return m.recordStore.GetMediaByFilename(ctx, path.Base(link))
+}
+
 func (m Media) GetMedia(ctx context.Context, id string) (*model.MediaRecord, error) {
 	media, err := m.recordStore.GetMedia(ctx, id)
 	if err != nil {
@@ -76,3 +142,73 @@ func (m Media) GetMedia(ctx context.Context, id string) (*model.MediaRecord, err
 	}
 	return media, nil
 }
+
+// GetUnsharedUserMedia returns the media uploaded by the user which isn't linked from quests of other users,
+// the files which can go when the user is deleted.
+func (m Media) GetUnsharedUserMedia(ctx context.Context, userId string) ([]model.MediaRecord, error) {
+	return m.recordStore.GetUnsharedMediaByOwner(ctx, userId)
+}
+
+// GetUserMedia returns all media uploaded by the user.
+func (m Media) GetUserMedia(ctx context.Context, userId string) ([]model.MediaRecord, error) {
+	return m.recordStore.GetMediaByOwner(ctx, userId)
+}
+
+// OpenFile opens the stored file of the media record for reading.
+func (m Media) OpenFile(ctx context.Context, record *model.MediaRecord) (io.ReadCloser, error) {
+	f, err := m.fileStorage.Open(ctx, record.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	return f, nil
+}
+
+// DeleteFiles removes the stored files of media records already deleted, like the ones of a deleted user.
+// Files which can't be removed are left behind and only logged.
+func (m Media) DeleteFiles(ctx context.Context, records []model.MediaRecord) {
+	for i := range records {
+		if err := m.fileStorage.Delete(ctx, records[i].Filename); err != nil {
+			logging.From(ctx).Error("failed to delete media file", zap.String("id", records[i].ID), zap.Error(err))
+			continue
+		}
+
+		m.events.Produce(ctx, events.TopicMedia, events.MediaEvent{
+			EventType: events.EventTypeUserDeleted,
+			ID:        records[i].ID,
+		})
+	}
+}
+
+// saveFile stores the filename and link of the record once its file is stored, the file and the record are removed
+// if that fails.
+func (m Media) saveFile(ctx context.Context, record *model.MediaRecord) (*model.MediaRecord, error) {
+	updated, err := m.recordStore.UpdateMedia(ctx, record)
+	if err != nil {
+		m.discard(ctx, record)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// discard removes the media whose file couldn't be stored, along with whatever part of the file was written.
+// Failures are only logged.
+func (m Media) discard(ctx context.Context, record *model.MediaRecord) {
+	if err := m.fileStorage.Delete(ctx, record.Filename); err != nil {
+		logging.From(ctx).Error("failed to delete media file", zap.String("id", record.ID), zap.Error(err))
+	}
+	if err := m.recordStore.DeleteMedia(ctx, record.ID); err != nil {
+		logging.From(ctx).Error("failed to delete media record", zap.String("id", record.ID), zap.Error(err))
+	}
+}
+
+// link returns the URL the file is served under.
+func link(filename string, mediaType model.MediaType) string {
+	staticFilesEndpoint := os.Getenv("STATIC_FILES_ENDPOINT")
+	if staticFilesEndpoint == "" || mediaType == model.Sound {
+		staticFilesEndpoint = "/files/"
+	}
+	return fmt.Sprintf("%s%s", staticFilesEndpoint, filename)
+}
@@ -6,6 +6,7 @@ import (
 
 type MediaRecord struct {
 	ID        string     `json:"id" db:"id"`
+	Owner     *string    `json:"-" db:"owner"`
 	Storage   string     `json:"-" db:"storage"`
 	Type      MediaType  `json:"type" db:"type"`
 	Filename  string     `json:"filename" db:"filename"`
@@ -14,6 +14,7 @@ import (
 type DB interface {
 	NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error)
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
@@ -37,8 +38,8 @@ func (s *RecordStore) InsertMedia(ctx context.Context, m *model.MediaRecord) (*m
 
 	res, err := s.db.NamedQueryContext(ctx,
 		`INSERT INTO 
-		media(storage, type, filename, link, created_at, updated_at) 
-		VALUES (:storage, :type, :filename, :link, :created_at, :updated_at) 
+		media(owner, storage, type, filename, link, created_at, updated_at) 
+		VALUES (:owner, :storage, :type, :filename, :link, :created_at, :updated_at) 
 		RETURNING *`, m)
 	if err != nil {
 		return nil, err
@@ -92,3 +93,54 @@ func (s *RecordStore) GetMedia(ctx context.Context, id string) (*model.MediaReco
 
 	return &m, nil
 }
+
+// GetMediaByFilename returns the media stored under the filename, which is the last part of its link.
+func (s *RecordStore) GetMediaByFilename(ctx context.Context, filename string) (*model.MediaRecord, error) {
+	var m model.MediaRecord
+
+	if err := s.db.GetContext(ctx, &m, "SELECT * FROM media WHERE filename = $1", filename); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &m, nil
+}
+
+// GetMediaByOwner returns all media uploaded by the user.
+func (s *RecordStore) GetMediaByOwner(ctx context.Context, ownerId string) ([]model.MediaRecord, error) {
+	m := []model.MediaRecord{}
+
+	if err := s.db.SelectContext(ctx, &m, "SELECT * FROM media WHERE owner = $1 ORDER BY created_at", ownerId); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return m, nil
+}
+
+// GetUnsharedMediaByOwner returns the media uploaded by the user which no quest of another user links to.
+func (s *RecordStore) GetUnsharedMediaByOwner(ctx context.Context, ownerId string) ([]model.MediaRecord, error) {
+	m := []model.MediaRecord{}
+
+	err := s.db.SelectContext(ctx, &m,
+		`SELECT * FROM media WHERE owner = $1
+		AND link NOT IN (SELECT s.question_content FROM steps s JOIN quests q ON q.id = s.quest_id
+		WHERE q.owner <> $1 AND s.question_content IS NOT NULL)
+		ORDER BY created_at`, ownerId)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return m, nil
+}
+
+// DeleteMedia deletes the media record.
+func (s *RecordStore) DeleteMedia(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM media WHERE id = $1", id); err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+
+	return nil
+}
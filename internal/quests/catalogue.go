@@ -0,0 +1,92 @@
+package quests
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+)
+
+// SetVisibility makes the quest public or private. Public quests get a slug to share, they are listed in the
+// catalogue and can be enrolled in while they are published.
+func (q *Quests) SetVisibility(ctx context.Context, id string, visibility model.Visibility) (*model.QuestWithSteps, error) {
+	if !visibility.IsValid() {
+		return nil, ErrInvalidVisibility.Wrap(errors.ErrValidation)
+	}
+	if _, err := q.getQuestWithAuthCheck(ctx, id, accessOwner); err != nil {
+		return nil, err
+	}
+
+	slug, err := newSlug()
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	if err := q.store.SetVisibility(ctx, id, visibility, slug); err != nil {
+		return nil, err
+	}
+
+	return q.getQuestWithAuthCheck(ctx, id, accessOwner)
+}
+
+// GetCatalogue returns the published public quests.
+func (q *Quests) GetCatalogue(ctx context.Context, filter model.CatalogueFilter) ([]model.CatalogueQuest, *model.Meta, error) {
+	return q.store.GetCatalogue(ctx, filter)
+}
+
+// GetCatalogueQuest returns the published public quest with the slug.
+func (q *Quests) GetCatalogueQuest(ctx context.Context, slug string) (*model.CatalogueQuest, error) {
+	return q.store.GetCatalogueQuest(ctx, slug)
+}
+
+// EnrollQuest sends the public quest with the slug to the email of the user, as if the owner had sent it.
+// Enrolling again returns the progress on the quest.
+func (q *Quests) EnrollQuest(ctx context.Context, slug string, email string, name string) (*model.QuestLine, error) {
+	cq, err := q.store.GetCatalogueQuest(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	ass, err := q.store.GetAssignmentByEmail(ctx, cq.ID, email)
+	if err == nil {
+		return q.getQuestLine(ctx, ass)
+	}
+	if !errors.Is(err, errors.ErrNotFound) {
+		return nil, err
+	}
+
+	quest, err := q.store.GetQuest(ctx, cq.ID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := q.saveVersion(ctx, quest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.store.CreateAssignment(ctx, model.SendQuestRequest{
+		QuestId:   cq.ID,
+		Email:     email,
+		Name:      name,
+		VersionId: &version.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ass, err = q.store.GetAssignmentByEmail(ctx, cq.ID, email)
+	if err != nil {
+		return nil, err
+	}
+	return q.getQuestLine(ctx, ass)
+}
+
+// newSlug returns a random url safe slug for sharing a public quest.
+func newSlug() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,69 @@
+package quests
+
+import (
+	"context"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"github.com/superhorsy/quest-app-backend/internal/transport/http"
+)
+
+// GetCollaborators returns the collaborators of the quest, to the owner and the collaborators themselves.
+func (q *Quests) GetCollaborators(ctx context.Context, questId string) ([]model.Collaborator, error) {
+	if _, err := q.getQuestWithAuthCheck(ctx, questId, accessView); err != nil {
+		return nil, err
+	}
+	return q.store.GetCollaborators(ctx, questId)
+}
+
+// ShareQuest invites the user to collaborate on the quest with the role, inviting a collaborator again changes their role.
+// Without a user nothing is saved, but the quest and the role are still checked, so the owner can't tell from the
+// result whether the email they invited has an account.
+func (q *Quests) ShareQuest(ctx context.Context, questId string, userId *string, role model.CollaboratorRole) error {
+	if !role.IsValid() {
+		return ErrInvalidCollaboratorRole.Wrap(errors.ErrValidation)
+	}
+
+	quest, err := q.getQuestWithAuthCheck(ctx, questId, accessOwner)
+	if err != nil {
+		return err
+	}
+	if userId == nil {
+		return nil
+	}
+	if *quest.Owner == *userId {
+		return ErrCollaboratorIsOwner.Wrap(errors.ErrValidation)
+	}
+
+	return q.store.SaveCollaborator(ctx, &model.Collaborator{
+		QuestId:   questId,
+		UserId:    *userId,
+		Role:      role,
+		InvitedBy: *quest.Owner,
+	})
+}
+
+// GetInvitations returns the quests the user was invited to collaborate on and hasn't accepted yet.
+func (q *Quests) GetInvitations(ctx context.Context, userId string) ([]model.SharedQuest, error) {
+	return q.store.GetInvitations(ctx, userId)
+}
+
+// AcceptInvitation gives the user access to the quest they were invited to, with the role of the invitation.
+func (q *Quests) AcceptInvitation(ctx context.Context, questId string) error {
+	return q.store.AcceptInvitation(ctx, questId, ctx.Value(http.ContextUserIdKey).(string))
+}
+
+// UnshareQuest stops collaborating on the quest, done by the owner or by the collaborator leaving the quest.
+func (q *Quests) UnshareQuest(ctx context.Context, questId string, userId string) error {
+	if ctx.Value(http.ContextUserIdKey).(string) != userId {
+		if _, err := q.getQuestWithAuthCheck(ctx, questId, accessOwner); err != nil {
+			return err
+		}
+	}
+	return q.store.DeleteCollaborator(ctx, questId, userId)
+}
+
+// GetSharedQuests returns the quests of other authors the user collaborates on.
+func (q *Quests) GetSharedQuests(ctx context.Context, userId string, offset int, limit int) ([]model.SharedQuest, *model.Meta, error) {
+	return q.store.GetSharedQuests(ctx, userId, offset, limit)
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// CollaboratorRole is what a collaborator may do with the quest of another author.
+type CollaboratorRole string
+
+const (
+	// CollaboratorEditor can change the quest and its steps.
+	CollaboratorEditor CollaboratorRole = "editor"
+	// CollaboratorViewer can only see the quest.
+	CollaboratorViewer CollaboratorRole = "viewer"
+)
+
+// IsValid checks the role is one of the known roles.
+func (r CollaboratorRole) IsValid() bool {
+	switch r {
+	case CollaboratorEditor, CollaboratorViewer:
+		return true
+	}
+	return false
+}
+
+// Collaborator represents a user the owner shared the quest with. The user gets access once they accept the invitation.
+type Collaborator struct {
+	QuestId    string           `json:"quest_id" db:"quest_id"`
+	UserId     string           `json:"user_id" db:"user_id"`
+	Nickname   *string          `json:"nickname" db:"nickname"`
+	FirstName  *string          `json:"first_name" db:"first_name"`
+	LastName   *string          `json:"last_name" db:"last_name"`
+	Role       CollaboratorRole `json:"role" db:"role"`
+	InvitedBy  string           `json:"invited_by" db:"invited_by"`
+	CreatedAt  *time.Time       `json:"created_at" db:"created_at"`
+	AcceptedAt *time.Time       `json:"accepted_at" db:"accepted_at"`
+}
+
+// IsAccepted checks the user accepted the invitation to collaborate.
+func (c *Collaborator) IsAccepted() bool {
+	return c.AcceptedAt != nil
+}
+
+// SharedQuest represents a quest of another author the user collaborates on.
+type SharedQuest struct {
+	Quest
+	Role CollaboratorRole `json:"role" db:"role"`
+}
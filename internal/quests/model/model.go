@@ -3,6 +3,7 @@ package model
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
 	"sort"
 	"strings"
@@ -18,6 +19,11 @@ const (
 	QuestionImage QuestionType = "image"
 )
 
+// IsValid checks the question type is one of the known types.
+func (t QuestionType) IsValid() bool {
+	return t == QuestionText || t == QuestionQR || t == QuestionSound || t == QuestionImage
+}
+
 type AnswerType string
 
 const (
@@ -69,12 +75,127 @@ const (
 	ThemeCommon    Theme = "common"
 )
 
+// State represents the lifecycle of a quest.
+type State string
+
+const (
+	// StateDraft quests are being written and can't be sent.
+	StateDraft State = "draft"
+	// StatePublished quests passed validation and can be sent.
+	StatePublished State = "published"
+	// StateArchived quests can't be sent anymore, their recipients can still play them.
+	StateArchived State = "archived"
+)
+
+// IsValid checks the state is one of the known states.
+func (s State) IsValid() bool {
+	return s == StateDraft || s == StatePublished || s == StateArchived
+}
+
+// Visibility decides who can find and play a quest.
+type Visibility string
+
+const (
+	// VisibilityPrivate quests are played only by the recipients they were sent to.
+	VisibilityPrivate Visibility = "private"
+	// VisibilityPublic quests are listed in the catalogue and anyone signed in can enroll via their slug.
+	VisibilityPublic Visibility = "public"
+)
+
+// IsValid checks the visibility is one of the known values.
+func (v Visibility) IsValid() bool {
+	return v == VisibilityPrivate || v == VisibilityPublic
+}
+
 // QuestWithSteps represents a quest
 type QuestWithSteps struct {
 	Quest
 	Steps []Step `json:"steps"`
 }
 
+// Validate returns what keeps the quest from being published, nothing when it is ready to be sent.
+func (q *QuestWithSteps) Validate() []string {
+	problems := []string{}
+
+	if q.Name == nil || strings.TrimSpace(*q.Name) == "" {
+		problems = append(problems, "name is empty")
+	}
+	if len(q.Steps) == 0 {
+		problems = append(problems, "quest has no steps")
+	}
+
+	for i, step := range q.Steps {
+		n := i + 1
+		if step.Sort != nil {
+			n = *step.Sort
+		}
+		if step.Description == nil || strings.TrimSpace(*step.Description) == "" {
+			problems = append(problems, fmt.Sprintf("step %d: description is empty", n))
+		}
+		if step.QuestionType == nil || !step.QuestionType.IsValid() {
+			problems = append(problems, fmt.Sprintf("step %d: question_type is invalid", n))
+		}
+		if step.QuestionContent == nil || strings.TrimSpace(*step.QuestionContent) == "" {
+			problems = append(problems, fmt.Sprintf("step %d: question_content is empty", n))
+		}
+		if step.AnswerType == nil || *step.AnswerType != AnswerText {
+			problems = append(problems, fmt.Sprintf("step %d: answer_type is invalid", n))
+		}
+		if !step.hasAnswer() {
+			problems = append(problems, fmt.Sprintf("step %d: answer_content has no answer", n))
+		}
+	}
+
+	return problems
+}
+
+func (s *Step) hasAnswer() bool {
+	if s.AnswerContent == nil {
+		return false
+	}
+	for _, answer := range *s.AnswerContent {
+		if strings.TrimSpace(answer) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Draft copies the content of the quest into a new draft of the owner, without its recipients. Question contents
+// found in links are replaced, which is used to point the copy to copies of the media.
+func (q *QuestWithSteps) Draft(owner string, links map[string]string) *QuestWithSteps {
+	draft := &QuestWithSteps{
+		Quest: Quest{
+			Name:         q.Name,
+			Description:  q.Description,
+			Owner:        &owner,
+			Theme:        q.Theme,
+			FinalMessage: q.FinalMessage,
+			Rewards:      q.Rewards,
+		},
+		Steps: make([]Step, 0, len(q.Steps)),
+	}
+
+	for _, step := range q.Steps {
+		step.ID, step.QuestId = nil, nil
+		step.CreatedAt, step.UpdatedAt, step.DeletedAt = nil, nil, nil
+		if step.QuestionContent != nil {
+			if link, ok := links[*step.QuestionContent]; ok {
+				step.QuestionContent = &link
+			}
+		}
+		draft.Steps = append(draft.Steps, step)
+	}
+
+	return draft
+}
+
+// HasMedia reports whether the question of the step is an uploaded file, linked in the question content.
+func (s *Step) HasMedia() bool {
+	return s.QuestionType != nil && s.QuestionContent != nil &&
+		(*s.QuestionType == QuestionImage || *s.QuestionType == QuestionSound)
+}
+
 // Quest represents a quest
 type Quest struct {
 	ID           *string     `json:"id" db:"id"`
@@ -85,6 +206,10 @@ type Quest struct {
 	Recipients   []Recipient `json:"recipients"`
 	FinalMessage *string     `json:"final_message" db:"final_message"`
 	Rewards      *Rewards    `json:"rewards" db:"rewards"`
+	State        *State      `json:"state" db:"state"`
+	PublishedAt  *time.Time  `json:"published_at" db:"published_at"`
+	Visibility   *Visibility `json:"visibility" db:"visibility"`
+	Slug         *string     `json:"slug" db:"slug"`
 
 	CreatedAt *time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
@@ -130,6 +255,119 @@ type Assignment struct {
 	Name        string `json:"name" db:"name"`
 	Status      Status `json:"status" db:"status"`
 	CurrentStep int    `json:"current_step" db:"current_step"`
+	// VersionId is the version of the quest the assignment plays, nil for quests sent before versions existed
+	VersionId *string `json:"version_id" db:"version_id"`
+}
+
+// QuestVersion represents an immutable snapshot of a quest taken when it is sent.
+type QuestVersion struct {
+	ID          string     `json:"id" db:"id"`
+	QuestId     string     `json:"quest_id" db:"quest_id"`
+	Version     int        `json:"version" db:"version"`
+	Snapshot    *Snapshot  `json:"quest,omitempty" db:"snapshot"`
+	ContentHash string     `json:"-" db:"content_hash"`
+	Recipients  int        `json:"recipients" db:"recipients"`
+	CreatedAt   *time.Time `json:"created_at" db:"created_at"`
+}
+
+// Snapshot represents the content of a quest version, without its recipients and timestamps.
+type Snapshot struct {
+	QuestWithSteps
+}
+
+// NewSnapshot copies the content of the quest, with the steps in their order.
+func NewSnapshot(quest *QuestWithSteps) *Snapshot {
+	s := &Snapshot{QuestWithSteps: *quest}
+	s.Recipients = nil
+	s.State, s.PublishedAt = nil, nil
+	s.Visibility, s.Slug = nil, nil
+	s.CreatedAt, s.UpdatedAt, s.DeletedAt = nil, nil, nil
+
+	s.Steps = make([]Step, len(quest.Steps))
+	copy(s.Steps, quest.Steps)
+	sort.Slice(s.Steps, func(i, j int) bool {
+		return *s.Steps[i].Sort < *s.Steps[j].Sort
+	})
+	for i := range s.Steps {
+		s.Steps[i].CreatedAt, s.Steps[i].UpdatedAt, s.Steps[i].DeletedAt = nil, nil, nil
+	}
+
+	return s
+}
+
+// Value Make the Snapshot struct implement the driver.Valuer interface.
+func (s *Snapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan Make the Snapshot struct implement the sql.Scanner interface.
+func (s *Snapshot) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, s)
+}
+
+// MapStep returns the sort in the snapshot of the step a player is at, given the sort of the step in the
+// previous version of the quest. Steps are matched by ID; when the step was removed, or the previous version
+// is unknown, the player continues with the first step from the same sort on, or the last step.
+func (s *Snapshot) MapStep(previous *Snapshot, currentStep int) int {
+	if previous != nil {
+		for _, p := range previous.Steps {
+			if *p.Sort != currentStep || p.ID == nil {
+				continue
+			}
+			for _, step := range s.Steps {
+				if step.ID != nil && *step.ID == *p.ID {
+					return *step.Sort
+				}
+			}
+		}
+	}
+
+	for _, step := range s.Steps {
+		if *step.Sort >= currentStep {
+			return *step.Sort
+		}
+	}
+	return *s.Steps[len(s.Steps)-1].Sort
+}
+
+// BundleVersion is the version of the layout of quest bundles, raised on changes older versions can't read.
+const BundleVersion = 1
+
+// Bundle represents the manifest of a quest exported to move it to another account or environment. The media
+// files of the steps are stored next to it in the archive.
+type Bundle struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Quest      *QuestWithSteps `json:"quest"`
+	Media      []BundleMedia   `json:"media"`
+}
+
+// BundleMedia represents a media file of a bundle and the link to it in the question contents.
+type BundleMedia struct {
+	Link string `json:"link"`
+	Type string `json:"type"`
+	File string `json:"file"`
+}
+
+// CloneQuestRequest represents the options of copying a quest. Without a name the copy keeps the name of the quest.
+type CloneQuestRequest struct {
+	Name      *string `json:"name"`
+	CopyMedia bool    `json:"copy_media"`
+}
+
+// MigrateRecipientsRequest represents the recipients to move to the latest version of a quest, all of them when empty.
+type MigrateRecipientsRequest struct {
+	Emails []string `json:"emails"`
+}
+
+// MigrateRecipientsResponse represents the version recipients were moved to.
+type MigrateRecipientsResponse struct {
+	Version  *QuestVersion `json:"version"`
+	Migrated int           `json:"migrated"`
 }
 
 type Owner struct {
@@ -148,6 +386,66 @@ type QuestAvailable struct {
 	Owner            *Owner `json:"owner"`
 }
 
+// QuestSummary represents a published quest shown on the public profile of its owner, without spoilers. Public
+// quests come with their slug.
+type QuestSummary struct {
+	ID          string     `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Description *string    `json:"description" db:"description"`
+	Theme       *Theme     `json:"theme" db:"theme"`
+	StepsCount  int        `json:"steps_count" db:"steps_count"`
+	PublishedAt *time.Time `json:"published_at" db:"published_at"`
+	Slug        *string    `json:"slug,omitempty" db:"slug"`
+}
+
+// CatalogueQuest represents a public quest in the catalogue.
+type CatalogueQuest struct {
+	QuestSummary
+	Author  string `json:"author" db:"author"`
+	Players int    `json:"players" db:"players"`
+}
+
+// CatalogueSort is the order of the catalogue.
+type CatalogueSort string
+
+const (
+	// CatalogueRecent lists the latest published quests first.
+	CatalogueRecent CatalogueSort = "recent"
+	// CataloguePopular lists the quests with the most players first.
+	CataloguePopular CatalogueSort = "popular"
+)
+
+// IsValid checks the sort is one of the known orders.
+func (s CatalogueSort) IsValid() bool {
+	return s == CatalogueRecent || s == CataloguePopular
+}
+
+// CatalogueFilter selects the quests of the catalogue.
+type CatalogueFilter struct {
+	Theme  Theme
+	Sort   CatalogueSort
+	Offset int
+	Limit  int
+}
+
+// SetVisibilityRequest represents a change of the visibility of a quest.
+type SetVisibilityRequest struct {
+	Visibility Visibility `json:"visibility"`
+}
+
+// AuthorStats represents how the quests of an author are doing, shown on their public profile.
+type AuthorStats struct {
+	QuestsPublished int `json:"quests_published" db:"quests_published"`
+	TimesSent       int `json:"times_sent" db:"times_sent"`
+	TimesFinished   int `json:"times_finished" db:"times_finished"`
+}
+
+// Trash represents the deleted quests of a user and the deleted steps of the other quests.
+type Trash struct {
+	Quests []Quest `json:"quests"`
+	Steps  []Step  `json:"steps"`
+}
+
 type Meta struct {
 	TotalCount int `json:"total_count,omitempty" db:"total_count"`
 }
@@ -156,6 +454,10 @@ type SendQuestRequest struct {
 	QuestId string `json:"quest_id" db:"quest_id"`
 	Email   string `json:"email" db:"email"`
 	Name    string `json:"name" db:"name"`
+	// ContactId sends the quest to a contact from the address book instead of the email and the name
+	ContactId *string `json:"contact_id,omitempty" db:"-"`
+	// VersionId is the version the quest is sent with, taken by the service
+	VersionId *string `json:"-" db:"version_id"`
 }
 
 type Answer struct {
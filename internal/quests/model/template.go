@@ -0,0 +1,200 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+)
+
+// placeholder matches a variable in the texts of a template, like {{recipient_name}}.
+var placeholder = regexp.MustCompile(`{{\s*([a-z0-9_]+)\s*}}`)
+
+// variableName matches the whole name of a variable, the same names placeholders can refer to.
+var variableName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// IsValid checks the theme is one of the known themes.
+func (t Theme) IsValid() bool {
+	switch t {
+	case ThemeValentain, ThemeChristmas, ThemeBirthday, ThemeHalloween, ThemeCommon:
+		return true
+	}
+	return false
+}
+
+// Template represents a curated quest of a theme authors can create their quests from.
+type Template struct {
+	ID          string            `json:"id" db:"id"`
+	Theme       Theme             `json:"theme" db:"theme"`
+	Name        string            `json:"name" db:"name"`
+	Description *string           `json:"description" db:"description"`
+	Variables   TemplateVariables `json:"variables" db:"variables"`
+	Quest       *TemplateQuest    `json:"quest,omitempty" db:"content"`
+	StepsCount  int               `json:"steps_count" db:"steps_count"`
+
+	CreatedAt *time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TemplateVariable represents a value asked from the author, substituted for {{name}} in the texts of the quest.
+type TemplateVariable struct {
+	Name    string  `json:"name"`
+	Label   string  `json:"label"`
+	Default *string `json:"default,omitempty"`
+}
+
+type TemplateVariables []TemplateVariable
+
+// TemplateQuest represents the quest of a template, the steps are placeholders authors fill in.
+type TemplateQuest struct {
+	QuestWithSteps
+}
+
+// Value Make the TemplateVariables implement the driver.Valuer interface.
+func (v TemplateVariables) Value() (driver.Value, error) {
+	return json.Marshal(v)
+}
+
+// Scan Make the TemplateVariables implement the sql.Scanner interface.
+func (v *TemplateVariables) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Value Make the TemplateQuest struct implement the driver.Valuer interface.
+func (q *TemplateQuest) Value() (driver.Value, error) {
+	return json.Marshal(q)
+}
+
+// Scan Make the TemplateQuest struct implement the sql.Scanner interface.
+func (q *TemplateQuest) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, q)
+}
+
+// Validate returns what is wrong with the template, nothing when it can be saved.
+func (t *Template) Validate() []string {
+	problems := []string{}
+
+	if strings.TrimSpace(t.Name) == "" {
+		problems = append(problems, "name is empty")
+	}
+	if !t.Theme.IsValid() {
+		problems = append(problems, "theme is invalid")
+	}
+	if t.Quest == nil {
+		return append(problems, "quest is empty")
+	}
+
+	declared := map[string]bool{}
+	for _, v := range t.Variables {
+		if !variableName.MatchString(v.Name) {
+			problems = append(problems, fmt.Sprintf("variable %q: name may contain only a-z, 0-9 and _", v.Name))
+		}
+		if declared[v.Name] {
+			problems = append(problems, fmt.Sprintf("variable %q is declared more than once", v.Name))
+		}
+		declared[v.Name] = true
+	}
+	for _, text := range t.Quest.texts() {
+		for _, m := range placeholder.FindAllStringSubmatch(*text, -1) {
+			if !declared[m[1]] {
+				problems = append(problems, fmt.Sprintf("variable %q is not declared", m[1]))
+				declared[m[1]] = true
+			}
+		}
+	}
+
+	return problems
+}
+
+// NewQuest fills the variables of the template with the values, or their defaults, into a new quest.
+// Returns the names of the variables without a value instead.
+func (t *Template) NewQuest(values map[string]string) (*QuestWithSteps, []string) {
+	resolved := map[string]string{}
+	missing := []string{}
+	for _, v := range t.Variables {
+		if value, ok := values[v.Name]; ok {
+			resolved[v.Name] = value
+		} else if v.Default != nil {
+			resolved[v.Name] = *v.Default
+		} else {
+			missing = append(missing, v.Name)
+		}
+	}
+	if len(missing) != 0 {
+		return nil, missing
+	}
+
+	fill := func(text *string) *string {
+		if text == nil {
+			return nil
+		}
+		filled := placeholder.ReplaceAllStringFunc(*text, func(m string) string {
+			return resolved[placeholder.FindStringSubmatch(m)[1]]
+		})
+		return &filled
+	}
+
+	theme := t.Theme
+	q := &QuestWithSteps{
+		Quest: Quest{
+			Name:         fill(t.Quest.Name),
+			Description:  fill(t.Quest.Description),
+			Theme:        &theme,
+			FinalMessage: fill(t.Quest.FinalMessage),
+			Rewards:      t.Quest.Rewards,
+		},
+		Steps: make([]Step, 0, len(t.Quest.Steps)),
+	}
+	for _, step := range t.Quest.Steps {
+		step.ID, step.QuestId = nil, nil
+		step.CreatedAt, step.UpdatedAt, step.DeletedAt = nil, nil, nil
+		step.Description = fill(step.Description)
+		step.QuestionContent = fill(step.QuestionContent)
+		if step.AnswerContent != nil {
+			answers := make(AnswerContent, 0, len(*step.AnswerContent))
+			for i := range *step.AnswerContent {
+				answers = append(answers, *fill(&(*step.AnswerContent)[i]))
+			}
+			step.AnswerContent = &answers
+		}
+		q.Steps = append(q.Steps, step)
+	}
+
+	return q, nil
+}
+
+// texts returns the texts of the quest variables can be used in.
+func (q *TemplateQuest) texts() []*string {
+	texts := []*string{}
+	for _, text := range []*string{q.Name, q.Description, q.FinalMessage} {
+		if text != nil {
+			texts = append(texts, text)
+		}
+	}
+	for i := range q.Steps {
+		step := &q.Steps[i]
+		for _, text := range []*string{step.Description, step.QuestionContent} {
+			if text != nil {
+				texts = append(texts, text)
+			}
+		}
+		if step.AnswerContent != nil {
+			for j := range *step.AnswerContent {
+				texts = append(texts, &(*step.AnswerContent)[j])
+			}
+		}
+	}
+	return texts
+}
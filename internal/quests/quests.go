@@ -2,24 +2,87 @@ package quests
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
 	"github.com/superhorsy/quest-app-backend/internal/events"
+	mediaModel "github.com/superhorsy/quest-app-backend/internal/media/model"
 	"github.com/superhorsy/quest-app-backend/internal/quests/model"
 	questStore "github.com/superhorsy/quest-app-backend/internal/quests/store"
 	"github.com/superhorsy/quest-app-backend/internal/transport/http"
+	"go.uber.org/zap"
+)
+
+const (
+	// ErrQuestNotPublished is returned when sending a quest which isn't published.
+	ErrQuestNotPublished = errors.Error("quest_not_published: only published quests can be sent")
+	// ErrQuestLocked is returned when removing or reordering steps of a published quest.
+	ErrQuestLocked = errors.Error("quest_locked: steps of a published quest can only be changed or added at the end")
+	// ErrQuestNotArchived is returned when deleting a published quest.
+	ErrQuestNotArchived = errors.Error("quest_not_archived: archive the published quest before deleting it")
+	// ErrInvalidStateTransition is returned when the quest can't move to the requested state.
+	ErrInvalidStateTransition = errors.Error("invalid_state_transition: only published quests can be archived")
+	// ErrInvalidCollaboratorRole is returned when sharing a quest with an unknown role.
+	ErrInvalidCollaboratorRole = errors.Error("invalid_collaborator_role: role must be editor or viewer")
+	// ErrCollaboratorIsOwner is returned when the owner shares the quest with themselves.
+	ErrCollaboratorIsOwner = errors.Error("collaborator_is_owner: the owner can't be a collaborator of the quest")
+	// ErrInvalidVisibility is returned when setting an unknown visibility.
+	ErrInvalidVisibility = errors.Error("invalid_visibility: visibility must be private or public")
 )
 
 // Store represents a type for storing a user in a database.
 type Store interface {
 	InsertQuest(ctx context.Context, quest *model.QuestWithSteps) (*model.QuestWithSteps, error)
 	GetQuest(ctx context.Context, id string) (*model.QuestWithSteps, error)
-	GetQuestsByUser(ctx context.Context, uuid string, offset int, limit int) ([]model.Quest, *model.Meta, error)
+	GetQuestWithDeleted(ctx context.Context, id string) (*model.QuestWithSteps, error)
+	GetQuestsByUser(ctx context.Context, uuid string, state model.State, offset int, limit int) ([]model.Quest, *model.Meta, error)
 	UpdateQuest(ctx context.Context, quest *model.QuestWithSteps) (*model.QuestWithSteps, error)
 	DeleteQuest(ctx context.Context, id string) error
 	GetQuestsAvailable(ctx context.Context, email string, offset int, limit int, finished bool) ([]model.QuestAvailable, *model.Meta, error)
 	CreateAssignment(ctx context.Context, request model.SendQuestRequest) error
 	GetAssignment(ctx context.Context, questId string, userId string) (*model.Assignment, error)
-	UpdateAssignment(ctx context.Context, questId string, userId *string, currentStep int, status model.Status) error
+	GetAssignmentByEmail(ctx context.Context, questId string, email string) (*model.Assignment, error)
+	UpdateAssignment(ctx context.Context, questId string, email string, currentStep int, status model.Status) error
+	ReassignAssignment(ctx context.Context, questId string, fromEmail string, toEmail string) error
+	GetQuestIDsByOwner(ctx context.Context, ownerId string) ([]string, error)
+	GetAssignmentsByEmail(ctx context.Context, email string) ([]model.Assignment, error)
+	GetPublishedQuests(ctx context.Context, ownerId string, offset int, limit int) ([]model.QuestSummary, *model.Meta, error)
+	GetAuthorStats(ctx context.Context, ownerId string) (*model.AuthorStats, error)
+	InsertStep(ctx context.Context, step *model.Step) (*model.Step, error)
+	UpdateStep(ctx context.Context, step *model.Step) (*model.Step, error)
+	DeleteStep(ctx context.Context, questId string, stepId string) error
+	ReorderSteps(ctx context.Context, questId string, stepIds []string) ([]model.Step, error)
+	SaveVersion(ctx context.Context, snapshot *model.Snapshot, contentHash string) (*model.QuestVersion, error)
+	GetVersions(ctx context.Context, questId string) ([]model.QuestVersion, error)
+	GetVersion(ctx context.Context, questId string, version int) (*model.QuestVersion, error)
+	GetVersionByID(ctx context.Context, id string) (*model.QuestVersion, error)
+	GetQuestAssignments(ctx context.Context, questId string, emails []string) ([]model.Assignment, error)
+	MigrateAssignments(ctx context.Context, assignments []model.Assignment) error
+	SetState(ctx context.Context, id string, state model.State) error
+	GetTrash(ctx context.Context, ownerId string) (*model.Trash, error)
+	RestoreQuest(ctx context.Context, id string, ownerId string) error
+	RestoreStep(ctx context.Context, questId string, stepId string, atEnd bool) (*model.Step, error)
+	PurgeTrash(ctx context.Context, before time.Time) ([]string, error)
+	InsertTemplate(ctx context.Context, t *model.Template) (*model.Template, error)
+	GetTemplates(ctx context.Context, theme model.Theme) ([]model.Template, error)
+	GetTemplate(ctx context.Context, id string) (*model.Template, error)
+	UpdateTemplate(ctx context.Context, t *model.Template) (*model.Template, error)
+	DeleteTemplate(ctx context.Context, id string) error
+	SaveCollaborator(ctx context.Context, c *model.Collaborator) error
+	AcceptInvitation(ctx context.Context, questId string, userId string) error
+	GetInvitations(ctx context.Context, userId string) ([]model.SharedQuest, error)
+	GetCollaborator(ctx context.Context, questId string, userId string) (*model.Collaborator, error)
+	GetCollaborators(ctx context.Context, questId string) ([]model.Collaborator, error)
+	DeleteCollaborator(ctx context.Context, questId string, userId string) error
+	GetSharedQuests(ctx context.Context, userId string, offset int, limit int) ([]model.SharedQuest, *model.Meta, error)
+	SetVisibility(ctx context.Context, id string, visibility model.Visibility, slug string) error
+	GetCatalogue(ctx context.Context, filter model.CatalogueFilter) ([]model.CatalogueQuest, *model.Meta, error)
+	GetCatalogueQuest(ctx context.Context, slug string) (*model.CatalogueQuest, error)
 }
 
 // Events represents a type for producing events on user CRUD operations.
@@ -27,52 +90,136 @@ type Events interface {
 	Produce(ctx context.Context, topic events.Topic, payload interface{})
 }
 
+// Media represents a type for copying the media linked in the steps of a quest.
+type Media interface {
+	GetMediaByLink(ctx context.Context, link string) (*mediaModel.MediaRecord, error)
+	CopyMedia(ctx context.Context, source *mediaModel.MediaRecord) (*mediaModel.MediaRecord, error)
+	DeleteMedia(ctx context.Context, record *mediaModel.MediaRecord) error
+}
+
 // Quests provides functionality for CRUD operations on a quests.
 type Quests struct {
 	store  Store
+	media  Media
 	events Events
 }
 
+// CreateAssignment sends the quest, pinning the recipient to the current content of the quest. Later edits
+// don't change what the recipient plays until they are migrated to a newer version.
 func (q *Quests) CreateAssignment(ctx context.Context, request model.SendQuestRequest) error {
-	_, err := q.getQuestWithAuthCheck(ctx, request.QuestId)
+	quest, err := q.getQuestWithAuthCheck(ctx, request.QuestId, accessOwner)
 	if err != nil {
 		return err
 	}
+	if *quest.State != model.StatePublished {
+		return ErrQuestNotPublished.Wrap(errors.ErrValidation)
+	}
+	version, err := q.saveVersion(ctx, quest)
+	if err != nil {
+		return err
+	}
+	request.VersionId = &version.ID
 	return q.store.CreateAssignment(ctx, request)
 }
 
 func (q *Quests) GetAssignment(ctx context.Context, questId string) (*model.QuestLine, error) {
-	quest, err := q.store.GetQuest(ctx, questId)
+	userId := ctx.Value(http.ContextUserIdKey).(string)
+	ass, err := q.store.GetAssignment(ctx, questId, userId)
 	if err != nil {
 		return nil, err
 	}
-	// Check if q has any steps
-	if len(quest.Steps) == 0 {
-		return nil, errors.ErrValidation.Wrap(errors.Error("can't get quest status: no steps found inside a quest"))
+	return q.getQuestLine(ctx, ass)
+}
+
+func (q *Quests) StartQuest(ctx context.Context, questId string, userId *string) (*model.QuestLine, error) {
+	ass, err := q.store.GetAssignment(ctx, questId, *userId)
+	if err != nil {
+		return nil, err
 	}
-	userId := ctx.Value(http.ContextUserIdKey).(string)
-	ass, err := q.store.GetAssignment(ctx, questId, userId)
+	return q.startAssignment(ctx, ass)
+}
+
+func (q *Quests) CheckAnswer(ctx context.Context, questId string, userId *string, answer *model.Answer) (*model.QuestLine, error) {
+	ass, err := q.store.GetAssignment(ctx, questId, *userId)
 	if err != nil {
 		return nil, err
 	}
-	return quest.NewQuestLine(&ass.CurrentStep, ass.Status), err
+	return q.answerAssignment(ctx, ass, answer)
 }
 
-func (q *Quests) StartQuest(ctx context.Context, questId string, userId *string) (*model.QuestLine, error) {
-	quest, err := q.store.GetQuest(ctx, questId)
+// GetGuestAssignment returns the progress of a guest playing the quest sent to the email via a magic link.
+func (q *Quests) GetGuestAssignment(ctx context.Context, questId string, email string) (*model.QuestLine, error) {
+	ass, err := q.store.GetAssignmentByEmail(ctx, questId, email)
+	if err != nil {
+		return nil, err
+	}
+	return q.getQuestLine(ctx, ass)
+}
+
+// StartGuestQuest starts the quest sent to the email for a guest playing via a magic link.
+func (q *Quests) StartGuestQuest(ctx context.Context, questId string, email string) (*model.QuestLine, error) {
+	ass, err := q.store.GetAssignmentByEmail(ctx, questId, email)
+	if err != nil {
+		return nil, err
+	}
+	return q.startAssignment(ctx, ass)
+}
+
+// CheckGuestAnswer checks the answer of a guest playing the quest sent to the email via a magic link.
+func (q *Quests) CheckGuestAnswer(ctx context.Context, questId string, email string, answer *model.Answer) (*model.QuestLine, error) {
+	ass, err := q.store.GetAssignmentByEmail(ctx, questId, email)
+	if err != nil {
+		return nil, err
+	}
+	return q.answerAssignment(ctx, ass, answer)
+}
+
+// ClaimAssignment moves the quest sent to the guest email, together with the progress on it, to the email of a user.
+func (q *Quests) ClaimAssignment(ctx context.Context, questId string, guestEmail string, email string) error {
+	if guestEmail == email {
+		// Already sent to the user
+		_, err := q.store.GetAssignmentByEmail(ctx, questId, email)
+		return err
+	}
+	return q.store.ReassignAssignment(ctx, questId, guestEmail, email)
+}
+
+// getAssignmentQuest returns the version of the quest the assignment is pinned to, or the quest itself for
+// assignments sent before versions existed. Quests in the trash can still be played.
+func (q *Quests) getAssignmentQuest(ctx context.Context, ass *model.Assignment) (*model.QuestWithSteps, error) {
+	if ass.VersionId == nil {
+		return q.store.GetQuestWithDeleted(ctx, ass.QuestId)
+	}
+	version, err := q.store.GetVersionByID(ctx, *ass.VersionId)
 	if err != nil {
 		return nil, err
 	}
+	return &version.Snapshot.QuestWithSteps, nil
+}
 
+func (q *Quests) getQuestLine(ctx context.Context, ass *model.Assignment) (*model.QuestLine, error) {
+	quest, err := q.getAssignmentQuest(ctx, ass)
+	if err != nil {
+		return nil, err
+	}
 	// Check if q has any steps
 	if len(quest.Steps) == 0 {
-		return nil, errors.ErrValidation.Wrap(errors.Error("can't start q: no steps found inside a q"))
+		return nil, errors.ErrValidation.Wrap(errors.Error("can't get quest status: no steps found inside a quest"))
 	}
+	return quest.NewQuestLine(&ass.CurrentStep, ass.Status), nil
+}
 
-	ass, err := q.store.GetAssignment(ctx, questId, *userId)
+func (q *Quests) startAssignment(ctx context.Context, ass *model.Assignment) (*model.QuestLine, error) {
+	quest, err := q.getAssignmentQuest(ctx, ass)
 	if err != nil {
 		return nil, err
 	}
+
+	// Check if q has any steps
+	if len(quest.Steps) == 0 {
+		return nil, errors.ErrValidation.Wrap(errors.Error("can't start q: no steps found inside a q"))
+	}
+
 	if ass.Status == model.StatusInProgress {
 		return nil, errors.New("quest already started")
 	}
@@ -84,12 +231,13 @@ func (q *Quests) StartQuest(ctx context.Context, questId string, userId *string)
 	ql := quest.NewQuestLine(nil, model.StatusInProgress)
 
 	// Save to DB
-	err = q.store.UpdateAssignment(ctx, questId, userId, *ql.List.Head.Value.Sort, model.StatusInProgress)
+	err = q.store.UpdateAssignment(ctx, ass.QuestId, ass.Email, *ql.List.Head.Value.Sort, model.StatusInProgress)
 
 	return ql, err
 }
-func (q *Quests) CheckAnswer(ctx context.Context, questId string, userId *string, answer *model.Answer) (*model.QuestLine, error) {
-	quest, err := q.store.GetQuest(ctx, questId)
+
+func (q *Quests) answerAssignment(ctx context.Context, ass *model.Assignment, answer *model.Answer) (*model.QuestLine, error) {
+	quest, err := q.getAssignmentQuest(ctx, ass)
 	if err != nil {
 		return nil, err
 	}
@@ -99,10 +247,6 @@ func (q *Quests) CheckAnswer(ctx context.Context, questId string, userId *string
 		return nil, errors.ErrValidation.Wrap(errors.Error("can't start q: no steps found inside a q"))
 	}
 
-	ass, err := q.store.GetAssignment(ctx, questId, *userId)
-	if err != nil {
-		return nil, err
-	}
 	if ass.Status != model.StatusInProgress {
 		return nil, errors.New("quest not in progress")
 	}
@@ -126,12 +270,13 @@ func (q *Quests) CheckAnswer(ctx context.Context, questId string, userId *string
 		ql.Rewards = quest.Rewards
 	}
 
-	return ql, q.store.UpdateAssignment(ctx, questId, userId, ql.CurrentStep(), ql.QuestStatus)
+	return ql, q.store.UpdateAssignment(ctx, ass.QuestId, ass.Email, ql.CurrentStep(), ql.QuestStatus)
 }
 
-func New(s *questStore.Store, e Events) *Quests {
+func New(s *questStore.Store, m Media, e Events) *Quests {
 	return &Quests{
 		store:  s,
+		media:  m,
 		events: e,
 	}
 }
@@ -151,28 +296,157 @@ func (q *Quests) CreateQuest(ctx context.Context, quest *model.QuestWithSteps) (
 	return createdQuest, nil
 }
 
-func (q *Quests) getQuestWithAuthCheck(ctx context.Context, id string) (*model.QuestWithSteps, error) {
+// CloneQuest copies the quest with its steps into a new draft of the user. With copyMedia the images and sounds of
+// the steps are copied too and the copy links to them, the copies are deleted again if the quest can't be cloned.
+func (q *Quests) CloneQuest(ctx context.Context, id string, name *string, copyMedia bool) (*model.QuestWithSteps, error) {
+	source, err := q.getQuestWithAuthCheck(ctx, id, accessView)
+	if err != nil {
+		return nil, err
+	}
+
+	links := map[string]string{}
+	var copies []*mediaModel.MediaRecord
+	if copyMedia {
+		if copies, err = q.copyMedia(ctx, source.Steps, links); err != nil {
+			return nil, err
+		}
+	}
+
+	clone := source.Draft(ctx.Value(http.ContextUserIdKey).(string), links)
+	if name != nil {
+		clone.Name = name
+	}
+
+	createdQuest, err := q.store.InsertQuest(ctx, clone)
+	if err != nil {
+		q.deleteMedia(ctx, copies)
+		return nil, err
+	}
+
+	q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
+		EventType: events.EventTypeQuestCreated,
+		ID:        *createdQuest.ID,
+		Quest:     createdQuest,
+		SourceID:  id,
+	})
+
+	return createdQuest, nil
+}
+
+// copyMedia copies the uploaded media linked in the steps and adds the links to the copies to links. Links to files
+// hosted elsewhere are kept as they are. If a copy fails the ones made so far are deleted.
+func (q *Quests) copyMedia(ctx context.Context, steps []model.Step, links map[string]string) ([]*mediaModel.MediaRecord, error) {
+	var copies []*mediaModel.MediaRecord
+	for _, step := range steps {
+		if !step.HasMedia() || links[*step.QuestionContent] != "" {
+			continue
+		}
+
+		media, err := q.media.GetMediaByLink(ctx, *step.QuestionContent)
+		if errors.Is(err, errors.ErrNotFound) {
+			continue
+		}
+		if err == nil {
+			media, err = q.media.CopyMedia(ctx, media)
+		}
+		if err != nil {
+			q.deleteMedia(ctx, copies)
+			return nil, err
+		}
+
+		copies = append(copies, media)
+		links[*step.QuestionContent] = media.Link
+	}
+
+	return copies, nil
+}
+
+// deleteMedia removes copies of media nothing links to, failures are only logged.
+func (q *Quests) deleteMedia(ctx context.Context, records []*mediaModel.MediaRecord) {
+	for _, record := range records {
+		if err := q.media.DeleteMedia(ctx, record); err != nil {
+			logging.From(ctx).Error("failed to delete media copy", zap.String("id", record.ID), zap.Error(err))
+		}
+	}
+}
+
+// ImportQuest creates a new draft of the user from the quest of a bundle, with the links to the media of the bundle
+// replaced by the links to the uploaded copies.
+func (q *Quests) ImportQuest(ctx context.Context, quest *model.QuestWithSteps, links map[string]string) (*model.QuestWithSteps, error) {
+	draft := quest.Draft(ctx.Value(http.ContextUserIdKey).(string), links)
+
+	createdQuest, err := q.store.InsertQuest(ctx, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
+		EventType: events.EventTypeQuestCreated,
+		ID:        *createdQuest.ID,
+		Quest:     createdQuest,
+	})
+
+	return createdQuest, nil
+}
+
+// access is what the user needs to be allowed to do with a quest.
+type access int
+
+const (
+	// accessView is granted to the owner and every collaborator.
+	accessView access = iota
+	// accessEdit is granted to the owner and editors.
+	accessEdit
+	// accessOwner is granted to the owner only.
+	accessOwner
+)
+
+// getQuestWithAuthCheck returns the quest if the user has the access to it. Recipients are shown to the owner only.
+func (q *Quests) getQuestWithAuthCheck(ctx context.Context, id string, need access) (*model.QuestWithSteps, error) {
 	quest, err := q.store.GetQuest(ctx, id)
 	if err != nil {
 		return &model.QuestWithSteps{}, err
 	}
 	uId := ctx.Value(http.ContextUserIdKey).(string)
-	if *quest.Owner != uId {
-		return nil, errors.ErrForbidden.Wrap(errors.Error("Пользователь не имеет доступа к квесту"))
+	if *quest.Owner == uId {
+		return quest, nil
 	}
-	return quest, nil
+
+	if need != accessOwner {
+		c, err := q.store.GetCollaborator(ctx, id, uId)
+		if err != nil && !errors.Is(err, errors.ErrNotFound) {
+			return nil, err
+		}
+		if c != nil && c.IsAccepted() && (c.Role == model.CollaboratorEditor || need == accessView) {
+			quest.Recipients = []model.Recipient{}
+			return quest, nil
+		}
+	}
+
+	return nil, errors.ErrForbidden.Wrap(errors.Error("Пользователь не имеет доступа к квесту"))
 }
 
 func (q *Quests) GetQuest(ctx context.Context, id string) (*model.QuestWithSteps, error) {
-	return q.getQuestWithAuthCheck(ctx, id)
+	return q.getQuestWithAuthCheck(ctx, id, accessView)
+}
+
+// InspectQuest returns any quest regardless of its owner, access to it is checked by the caller.
+func (q *Quests) InspectQuest(ctx context.Context, id string) (*model.QuestWithSteps, error) {
+	return q.store.GetQuest(ctx, id)
 }
 
-// UpdateQuest updates quests. If there were any steps inside it deletes them and insert new regardless of already created steps
+// UpdateQuest updates quests. Steps are matched by ID: known steps are updated, steps without an ID are added
+// and the ones left out are moved to the trash
 func (q *Quests) UpdateQuest(ctx context.Context, quest *model.QuestWithSteps) (*model.QuestWithSteps, error) {
-	_, err := q.getQuestWithAuthCheck(ctx, *quest.ID)
+	saved, err := q.getQuestWithAuthCheck(ctx, *quest.ID, accessEdit)
 	if err != nil {
 		return nil, err
 	}
+	if *saved.State != model.StateDraft {
+		if err := checkStepsKept(saved.Steps, quest.Steps); err != nil {
+			return nil, err
+		}
+	}
 	quest, err = q.store.UpdateQuest(ctx, quest)
 	if err != nil {
 		return nil, err
@@ -185,28 +459,394 @@ func (q *Quests) UpdateQuest(ctx context.Context, quest *model.QuestWithSteps) (
 	return quest, nil
 }
 
+// CreateStep adds a step to the quest, without a sort it is appended after the last step.
+func (q *Quests) CreateStep(ctx context.Context, questId string, step *model.Step) (*model.Step, error) {
+	quest, err := q.getQuestWithAuthCheck(ctx, questId, accessEdit)
+	if err != nil {
+		return nil, err
+	}
+	if *quest.State != model.StateDraft && step.Sort != nil && *step.Sort <= maxSort(quest.Steps) {
+		return nil, ErrQuestLocked.Wrap(errors.ErrValidation)
+	}
+	step.ID = nil
+	step.QuestId = &questId
+
+	createdStep, err := q.store.InsertStep(ctx, step)
+	if err != nil {
+		return nil, err
+	}
+	q.produceStepsChanged(ctx, questId)
+
+	return createdStep, nil
+}
+
+// UpdateStep changes the content of a step of the quest, keeping its ID and sort.
+func (q *Quests) UpdateStep(ctx context.Context, questId string, step *model.Step) (*model.Step, error) {
+	if _, err := q.getQuestWithAuthCheck(ctx, questId, accessEdit); err != nil {
+		return nil, err
+	}
+	step.QuestId = &questId
+
+	updatedStep, err := q.store.UpdateStep(ctx, step)
+	if err != nil {
+		return nil, err
+	}
+	q.produceStepsChanged(ctx, questId)
+
+	return updatedStep, nil
+}
+
+// DeleteStep removes a step from the quest.
+func (q *Quests) DeleteStep(ctx context.Context, questId string, stepId string) error {
+	if err := q.checkDraft(ctx, questId); err != nil {
+		return err
+	}
+
+	if err := q.store.DeleteStep(ctx, questId, stepId); err != nil {
+		return err
+	}
+	q.produceStepsChanged(ctx, questId)
+
+	return nil
+}
+
+// ReorderSteps puts the steps of the quest into the order of the IDs, which must list every step exactly once.
+func (q *Quests) ReorderSteps(ctx context.Context, questId string, stepIds []string) ([]model.Step, error) {
+	if err := q.checkDraft(ctx, questId); err != nil {
+		return nil, err
+	}
+
+	steps, err := q.store.ReorderSteps(ctx, questId, stepIds)
+	if err != nil {
+		return nil, err
+	}
+	q.produceStepsChanged(ctx, questId)
+
+	return steps, nil
+}
+
+// checkDraft checks the user may edit the quest and it isn't published yet, so its steps can be removed or reordered.
+func (q *Quests) checkDraft(ctx context.Context, questId string) error {
+	quest, err := q.getQuestWithAuthCheck(ctx, questId, accessEdit)
+	if err != nil {
+		return err
+	}
+	if *quest.State != model.StateDraft {
+		return ErrQuestLocked.Wrap(errors.ErrValidation)
+	}
+	return nil
+}
+
+// checkStepsKept checks an update of a published quest keeps every saved step at its sort and adds new steps
+// only after them, so that recipients can be migrated to the new version.
+func checkStepsKept(saved []model.Step, updated []model.Step) error {
+	sorts := map[string]int{}
+	for _, step := range updated {
+		if step.ID != nil && step.Sort != nil {
+			sorts[*step.ID] = *step.Sort
+		}
+	}
+
+	for _, step := range saved {
+		if sort, ok := sorts[*step.ID]; !ok || sort != *step.Sort {
+			return ErrQuestLocked.Wrap(errors.ErrValidation)
+		}
+	}
+
+	last := maxSort(saved)
+	for _, step := range updated {
+		if step.ID == nil && (step.Sort == nil || *step.Sort <= last) {
+			return ErrQuestLocked.Wrap(errors.ErrValidation)
+		}
+	}
+
+	return nil
+}
+
+func maxSort(steps []model.Step) int {
+	last := 0
+	for _, step := range steps {
+		if *step.Sort > last {
+			last = *step.Sort
+		}
+	}
+	return last
+}
+
+// PublishQuest validates the quest and makes it available for sending, saving its content as a version.
+// Archived quests can be published again.
+func (q *Quests) PublishQuest(ctx context.Context, id string) (*model.QuestWithSteps, error) {
+	quest, err := q.getQuestWithAuthCheck(ctx, id, accessOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	if problems := quest.Validate(); len(problems) != 0 {
+		return nil, errors.Error("quest_invalid: " + strings.Join(problems, "; ")).Wrap(errors.ErrValidation)
+	}
+
+	if _, err := q.saveVersion(ctx, quest); err != nil {
+		return nil, err
+	}
+
+	return q.setState(ctx, id, model.StatePublished)
+}
+
+// ArchiveQuest stops the published quest from being sent, its recipients can still play it.
+func (q *Quests) ArchiveQuest(ctx context.Context, id string) (*model.QuestWithSteps, error) {
+	quest, err := q.getQuestWithAuthCheck(ctx, id, accessOwner)
+	if err != nil {
+		return nil, err
+	}
+	if *quest.State != model.StatePublished {
+		return nil, ErrInvalidStateTransition.Wrap(errors.ErrValidation)
+	}
+
+	return q.setState(ctx, id, model.StateArchived)
+}
+
+func (q *Quests) setState(ctx context.Context, id string, state model.State) (*model.QuestWithSteps, error) {
+	if err := q.store.SetState(ctx, id, state); err != nil {
+		return nil, err
+	}
+
+	quest, err := q.store.GetQuest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
+		EventType: events.EventTypeQuestUpdated,
+		ID:        id,
+		Quest:     quest,
+	})
+
+	return quest, nil
+}
+
+func (q *Quests) produceStepsChanged(ctx context.Context, questId string) {
+	q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
+		EventType: events.EventTypeQuestUpdated,
+		ID:        questId,
+	})
+}
+
+// GetVersions returns the versions the quest was sent with, newest first.
+func (q *Quests) GetVersions(ctx context.Context, questId string) ([]model.QuestVersion, error) {
+	if _, err := q.getQuestWithAuthCheck(ctx, questId, accessView); err != nil {
+		return nil, err
+	}
+	return q.store.GetVersions(ctx, questId)
+}
+
+// GetVersion returns a version of the quest with its content.
+func (q *Quests) GetVersion(ctx context.Context, questId string, version int) (*model.QuestVersion, error) {
+	if _, err := q.getQuestWithAuthCheck(ctx, questId, accessView); err != nil {
+		return nil, err
+	}
+	return q.store.GetVersion(ctx, questId, version)
+}
+
+// MigrateRecipients moves recipients of the quest, all of them unless emails are given, to a version with the
+// current content of the quest. Players keep their progress: they continue at the step they were at, matched by ID.
+func (q *Quests) MigrateRecipients(ctx context.Context, questId string, emails []string) (*model.MigrateRecipientsResponse, error) {
+	quest, err := q.getQuestWithAuthCheck(ctx, questId, accessOwner)
+	if err != nil {
+		return nil, err
+	}
+	if len(quest.Steps) == 0 {
+		return nil, errors.ErrValidation.Wrap(errors.Error("can't migrate recipients: no steps found inside a quest"))
+	}
+
+	version, err := q.saveVersion(ctx, quest)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := model.NewSnapshot(quest)
+
+	assignments, err := q.store.GetQuestAssignments(ctx, questId, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := map[string]*model.Snapshot{}
+	migrated := make([]model.Assignment, 0, len(assignments))
+	for _, a := range assignments {
+		if a.VersionId != nil && *a.VersionId == version.ID {
+			continue
+		}
+
+		// The layout of quests sent before versions existed is unknown, their players are matched by the sort only
+		var from *model.Snapshot
+		if a.VersionId != nil {
+			if _, ok := previous[*a.VersionId]; !ok {
+				v, err := q.store.GetVersionByID(ctx, *a.VersionId)
+				if err != nil {
+					return nil, err
+				}
+				previous[*a.VersionId] = v.Snapshot
+			}
+			from = previous[*a.VersionId]
+		}
+
+		// Players who haven't started yet begin at the first step anyway
+		if a.Status != model.StatusNotStarted {
+			a.CurrentStep = snapshot.MapStep(from, a.CurrentStep)
+		}
+		a.VersionId = &version.ID
+		migrated = append(migrated, a)
+	}
+
+	if err := q.store.MigrateAssignments(ctx, migrated); err != nil {
+		return nil, err
+	}
+
+	// Reload to count the recipients of the version
+	version, err = q.store.GetVersion(ctx, questId, version.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MigrateRecipientsResponse{Version: version, Migrated: len(migrated)}, nil
+}
+
+// saveVersion snapshots the current content of the quest, reusing the latest version when nothing changed since.
+// It is called only where the content gets pinned (publish, send, enroll and migrate), edits just change the draft.
+func (q *Quests) saveVersion(ctx context.Context, quest *model.QuestWithSteps) (*model.QuestVersion, error) {
+	snapshot := model.NewSnapshot(quest)
+
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	sum := sha256.Sum256(content)
+
+	return q.store.SaveVersion(ctx, snapshot, hex.EncodeToString(sum[:]))
+}
+
+// DeleteQuest moves the quest to the trash, published quests have to be archived first.
 func (q *Quests) DeleteQuest(ctx context.Context, id string) error {
-	_, err := q.getQuestWithAuthCheck(ctx, id)
+	quest, err := q.getQuestWithAuthCheck(ctx, id, accessOwner)
 	if err != nil {
 		return err
 	}
+	if *quest.State == model.StatePublished {
+		return ErrQuestNotArchived.Wrap(errors.ErrValidation)
+	}
 	err = q.store.DeleteQuest(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
-		EventType: events.EventTypeQuestDeleted,
+		EventType: events.EventTypeQuestTrashed,
 		ID:        id,
 	})
 
 	return nil
 }
 
-func (q *Quests) GetQuestsByUser(ctx context.Context, ownerUuid string, offset int, limit int) ([]model.Quest, *model.Meta, error) {
-	return q.store.GetQuestsByUser(ctx, ownerUuid, offset, limit)
+// GetTrash returns the deleted quests of the user and the deleted steps of the other quests.
+func (q *Quests) GetTrash(ctx context.Context, userId string) (*model.Trash, error) {
+	return q.store.GetTrash(ctx, userId)
+}
+
+// RestoreQuest takes the quest of the user out of the trash.
+func (q *Quests) RestoreQuest(ctx context.Context, id string) (*model.QuestWithSteps, error) {
+	userId := ctx.Value(http.ContextUserIdKey).(string)
+	if err := q.store.RestoreQuest(ctx, id, userId); err != nil {
+		return nil, err
+	}
+
+	quest, err := q.store.GetQuest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
+		EventType: events.EventTypeQuestUpdated,
+		ID:        id,
+		Quest:     quest,
+	})
+
+	return quest, nil
+}
+
+// RestoreStep takes the step out of the trash. Steps of published quests are appended after the last step,
+// the others keep their sort if it is still free.
+func (q *Quests) RestoreStep(ctx context.Context, questId string, stepId string) (*model.Step, error) {
+	quest, err := q.getQuestWithAuthCheck(ctx, questId, accessEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	step, err := q.store.RestoreStep(ctx, questId, stepId, *quest.State != model.StateDraft)
+	if err != nil {
+		return nil, err
+	}
+	q.produceStepsChanged(ctx, questId)
+
+	return step, nil
+}
+
+// PurgeTrash deletes for good the steps and quests in the trash for longer than the retention,
+// except quests which were sent.
+func (q *Quests) PurgeTrash(ctx context.Context, retention time.Duration) error {
+	ids, err := q.store.PurgeTrash(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
+			EventType: events.EventTypeQuestDeleted,
+			ID:        id,
+		})
+	}
+
+	return nil
+}
+
+// GetQuestsByUser returns the quests created by the user, only the ones in the state unless it is empty.
+func (q *Quests) GetQuestsByUser(ctx context.Context, ownerUuid string, state model.State, offset int, limit int) ([]model.Quest, *model.Meta, error) {
+	return q.store.GetQuestsByUser(ctx, ownerUuid, state, offset, limit)
 }
 
 func (q *Quests) GetQuestsAvailable(ctx context.Context, email string, offset int, limit int, finished bool) ([]model.QuestAvailable, *model.Meta, error) {
 	return q.store.GetQuestsAvailable(ctx, email, offset, limit, finished)
 }
+
+// GetPublishedQuests returns the published public quests of the user shown on their public profile,
+// private quests are only sent by the author even once published.
+func (q *Quests) GetPublishedQuests(ctx context.Context, ownerId string, offset int, limit int) ([]model.QuestSummary, *model.Meta, error) {
+	return q.store.GetPublishedQuests(ctx, ownerId, offset, limit)
+}
+
+// GetAuthorStats returns the statistics of the user shown on their public profile.
+func (q *Quests) GetAuthorStats(ctx context.Context, ownerId string) (*model.AuthorStats, error) {
+	return q.store.GetAuthorStats(ctx, ownerId)
+}
+
+// GetUserQuests returns all quests created by the user with their steps and recipients, including the trash.
+func (q *Quests) GetUserQuests(ctx context.Context, userId string) ([]model.QuestWithSteps, error) {
+	ids, err := q.store.GetQuestIDsByOwner(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	quests := make([]model.QuestWithSteps, 0, len(ids))
+	for _, id := range ids {
+		quest, err := q.store.GetQuestWithDeleted(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		quests = append(quests, *quest)
+	}
+
+	return quests, nil
+}
+
+// GetUserAssignments returns all quests sent to the email together with the progress on them.
+func (q *Quests) GetUserAssignments(ctx context.Context, email string) ([]model.Assignment, error) {
+	return q.store.GetAssignmentsByEmail(ctx, email)
+}
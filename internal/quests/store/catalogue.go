@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+)
+
+// ErrCatalogueQuestNotFound is returned when no public quest has the slug.
+const ErrCatalogueQuestNotFound = errors.Error("catalogue_quest_not_found: quest not found")
+
+// catalogueQuery selects the published public quests with their author and number of players.
+const catalogueQuery = `SELECT q.id, q.name, q.description, q.theme, q.published_at, q.slug,
+       (SELECT count(*) FROM steps s WHERE s.quest_id = q.id AND s.deleted_at IS NULL) AS steps_count,
+       u.nickname                                                                       AS author,
+       (SELECT count(*) FROM quest_to_email qe WHERE qe.quest_id = q.id)                AS players
+FROM quests q
+         JOIN users u ON u.id = q.owner
+WHERE q.visibility = 'public' AND q.state = 'published' AND q.deleted_at IS NULL AND u.disabled_at IS NULL`
+
+// SetVisibility will change the visibility of the quest. The slug is set only once, so shared links keep working.
+func (s *Store) SetVisibility(ctx context.Context, id string, visibility model.Visibility, slug string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE quests SET visibility = $1, slug = COALESCE(slug, $2), updated_at = $3
+			WHERE id = $4 AND deleted_at IS NULL`, visibility, slug, timeNow(), id)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// GetCatalogue returns the public quests of the theme, or of every theme when it is empty, in the order of the filter.
+func (s *Store) GetCatalogue(ctx context.Context, filter model.CatalogueFilter) ([]model.CatalogueQuest, *model.Meta, error) {
+	quests := []model.CatalogueQuest{}
+
+	order := `published_at DESC`
+	if filter.Sort == model.CataloguePopular {
+		order = `players DESC, published_at DESC`
+	}
+
+	err := s.db.SelectContext(ctx, &quests,
+		`SELECT * FROM (`+catalogueQuery+` AND ($1 = '' OR q.theme = $1)) c
+ORDER BY `+order+`
+OFFSET $2 LIMIT $3`, filter.Theme, filter.Offset, filter.Limit)
+	if err = checkWriteError(err); err != nil {
+		return nil, nil, err
+	}
+
+	var meta model.Meta
+
+	err = s.db.GetContext(ctx, &meta,
+		`SELECT count(*) AS total_count FROM (`+catalogueQuery+` AND ($1 = '' OR q.theme = $1)) c`, filter.Theme)
+	if err = checkWriteError(err); err != nil {
+		return nil, nil, err
+	}
+
+	return quests, &meta, nil
+}
+
+// GetCatalogueQuest returns the public quest with the slug.
+func (s *Store) GetCatalogueQuest(ctx context.Context, slug string) (*model.CatalogueQuest, error) {
+	var q model.CatalogueQuest
+
+	if err := s.db.GetContext(ctx, &q, catalogueQuery+` AND q.slug = $1`, slug); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCatalogueQuestNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &q, nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+)
+
+const (
+	// ErrCollaboratorNotFound is returned when the quest isn't shared with the user.
+	ErrCollaboratorNotFound = errors.Error("collaborator_not_found: collaborator not found")
+	// ErrInvitationNotFound is returned when accepting an invitation the user doesn't have or already accepted.
+	ErrInvitationNotFound = errors.Error("invitation_not_found: invitation not found")
+)
+
+const collaboratorColumns = `c.quest_id, c.user_id, u.nickname, u.first_name, u.last_name, c.role, c.invited_by, c.created_at, c.accepted_at`
+
+// SaveCollaborator will invite the user to collaborate on the quest, or change the role of a collaborator already invited.
+func (s *Store) SaveCollaborator(ctx context.Context, c *model.Collaborator) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO quest_collaborators(quest_id, user_id, role, invited_by, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (quest_id, user_id) DO UPDATE SET role = excluded.role`,
+		c.QuestId, c.UserId, c.Role, c.InvitedBy, timeNow())
+	return checkWriteError(err)
+}
+
+// AcceptInvitation will give the user access to the quest they were invited to.
+func (s *Store) AcceptInvitation(ctx context.Context, questId string, userId string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE quest_collaborators SET accepted_at = $3 WHERE quest_id = $1 AND user_id = $2 AND accepted_at IS NULL`,
+		questId, userId, timeNow())
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrInvitationNotFound.Wrap(errors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// GetCollaborator returns the collaborator of the quest.
+func (s *Store) GetCollaborator(ctx context.Context, questId string, userId string) (*model.Collaborator, error) {
+	var c model.Collaborator
+
+	err := s.db.GetContext(ctx, &c,
+		`SELECT `+collaboratorColumns+` FROM quest_collaborators c JOIN users u ON u.id = c.user_id
+		WHERE c.quest_id = $1 AND c.user_id = $2`, questId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCollaboratorNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &c, nil
+}
+
+// GetCollaborators returns the collaborators of the quest who accepted the invitation, in the order they were invited.
+func (s *Store) GetCollaborators(ctx context.Context, questId string) ([]model.Collaborator, error) {
+	collaborators := []model.Collaborator{}
+
+	err := s.db.SelectContext(ctx, &collaborators,
+		`SELECT `+collaboratorColumns+` FROM quest_collaborators c JOIN users u ON u.id = c.user_id
+		WHERE c.quest_id = $1 AND c.accepted_at IS NOT NULL ORDER BY c.created_at`, questId)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return collaborators, nil
+}
+
+// DeleteCollaborator will stop sharing the quest with the user.
+func (s *Store) DeleteCollaborator(ctx context.Context, questId string, userId string) error {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM quest_collaborators WHERE quest_id = $1 AND user_id = $2`, questId, userId)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrCollaboratorNotFound.Wrap(errors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// GetSharedQuests returns the quests of other authors shared with the user, with the role of the user on each.
+func (s *Store) GetSharedQuests(ctx context.Context, userId string, offset int, limit int) ([]model.SharedQuest, *model.Meta, error) {
+	quests := []model.SharedQuest{}
+
+	err := s.db.SelectContext(ctx, &quests,
+		`SELECT q.*, c.role FROM quests q JOIN quest_collaborators c ON c.quest_id = q.id
+		WHERE c.user_id = $1 AND c.accepted_at IS NOT NULL AND q.deleted_at IS NULL
+		ORDER BY q.updated_at DESC LIMIT $2 OFFSET $3`, userId, limit, offset)
+	if err = checkWriteError(err); err != nil {
+		return nil, nil, err
+	}
+
+	var meta model.Meta
+
+	err = s.db.GetContext(ctx, &meta,
+		`SELECT count(*) AS total_count FROM quests q JOIN quest_collaborators c ON c.quest_id = q.id
+		WHERE c.user_id = $1 AND c.accepted_at IS NOT NULL AND q.deleted_at IS NULL`, userId)
+	if err = checkWriteError(err); err != nil {
+		return nil, nil, err
+	}
+
+	return quests, &meta, nil
+}
+
+// GetInvitations returns the quests the user was invited to collaborate on and hasn't accepted yet, newest first.
+func (s *Store) GetInvitations(ctx context.Context, userId string) ([]model.SharedQuest, error) {
+	quests := []model.SharedQuest{}
+
+	err := s.db.SelectContext(ctx, &quests,
+		`SELECT q.*, c.role FROM quests q JOIN quest_collaborators c ON c.quest_id = q.id
+		WHERE c.user_id = $1 AND c.accepted_at IS NULL AND q.deleted_at IS NULL
+		ORDER BY c.created_at DESC`, userId)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return quests, nil
+}
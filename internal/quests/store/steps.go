@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+)
+
+// InsertStep will add a step to the quest, without a sort it is appended after the last step.
+func (s *Store) InsertStep(ctx context.Context, step *model.Step) (*model.Step, error) {
+	step.CreatedAt = timeNow()
+	step.UpdatedAt = step.CreatedAt
+
+	var created model.Step
+
+	err := s.db.GetContext(ctx, &created,
+		`INSERT INTO
+		steps(quest_id, sort, description, question_type, question_content, answer_type, answer_content, created_at, updated_at)
+		VALUES ($1, COALESCE($2, (SELECT COALESCE(max(sort), 0) + 1 FROM steps WHERE quest_id = $1 AND deleted_at IS NULL)), $3, $4, $5, $6, $7, $8, $8)
+		RETURNING *`,
+		step.QuestId, step.Sort, step.Description, step.QuestionType, step.QuestionContent, step.AnswerType, step.AnswerContent,
+		step.CreatedAt)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// UpdateStep will update the content of a step of the quest, its sort is changed only via ReorderSteps.
+func (s *Store) UpdateStep(ctx context.Context, step *model.Step) (*model.Step, error) {
+	step.UpdatedAt = timeNow()
+
+	var updated model.Step
+
+	err := s.db.GetContext(ctx, &updated,
+		`UPDATE steps SET description = $1, question_type = $2, question_content = $3, answer_type = $4,
+		answer_content = $5, updated_at = $6
+		WHERE id = $7 AND quest_id = $8 AND deleted_at IS NULL
+		RETURNING *`,
+		step.Description, step.QuestionType, step.QuestionContent, step.AnswerType, step.AnswerContent, step.UpdatedAt,
+		step.ID, step.QuestId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStepNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &updated, nil
+}
+
+// DeleteStep will move a step of the quest to the trash. The sort of the following steps is kept.
+func (s *Store) DeleteStep(ctx context.Context, questId string, stepId string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE steps SET deleted_at = $3 WHERE id = $1 AND quest_id = $2 AND deleted_at IS NULL`, stepId, questId, timeNow())
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrStepNotFound.Wrap(errors.ErrNotFound)
+	}
+
+	return nil
+}
+
+// ReorderSteps will put the steps of the quest into the given order. The steps swap their sort numbers in a single
+// statement, which the deferrable unique constraint on (quest_id, sort) allows.
+func (s *Store) ReorderSteps(ctx context.Context, questId string, stepIds []string) ([]model.Step, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var current []model.Step
+	err = tx.SelectContext(ctx, &current, `SELECT * FROM steps WHERE quest_id = $1 AND deleted_at IS NULL ORDER BY sort FOR UPDATE`, questId)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	if len(stepIds) != len(current) {
+		return nil, ErrInvalidStepOrder.Wrap(errors.ErrValidation)
+	}
+	saved := make(map[string]bool, len(current))
+	for i := range current {
+		saved[*current[i].ID] = true
+	}
+	// The steps take over the sort numbers in use in the new order
+	sorts := make([]int64, len(stepIds))
+	for i, id := range stepIds {
+		if !saved[id] {
+			return nil, ErrInvalidStepOrder.Wrap(errors.ErrValidation)
+		}
+		delete(saved, id)
+		sorts[i] = int64(*current[i].Sort)
+	}
+
+	steps := []model.Step{}
+	err = tx.SelectContext(ctx, &steps,
+		`UPDATE steps SET sort = o.sort, updated_at = $4
+		FROM unnest($1::uuid[], $2::int[]) AS o(id, sort)
+		WHERE steps.id = o.id AND steps.quest_id = $3
+		RETURNING steps.*`, pq.Array(stepIds), pq.Array(sorts), questId, timeNow())
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, checkWriteError(err)
+	}
+
+	sortSteps(steps)
+	return steps, nil
+}
+
+// syncSteps makes the saved steps of the quest match the given ones within the transaction: steps with an ID are
+// updated, the others are added and saved steps left out are moved to the trash. The sort uniqueness is checked
+// on commit, so steps can swap places.
+func syncSteps(ctx context.Context, tx *sqlx.Tx, questId string, steps []model.Step, now *time.Time) ([]model.Step, error) {
+	if _, err := tx.ExecContext(ctx, `SET CONSTRAINTS steps_sort_unique DEFERRED`); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	keep := []string{}
+	for i := range steps {
+		if steps[i].ID != nil && *steps[i].ID != "" {
+			keep = append(keep, *steps[i].ID)
+		}
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`UPDATE steps SET deleted_at = $3 WHERE quest_id = $1 AND deleted_at IS NULL AND NOT (id = ANY($2::uuid[]))`,
+		questId, pq.Array(keep), now)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	saved := make([]model.Step, 0, len(steps))
+	for i := range steps {
+		step := steps[i]
+		var s model.Step
+
+		if step.ID != nil && *step.ID != "" {
+			err = tx.GetContext(ctx, &s,
+				`UPDATE steps SET sort = $1, description = $2, question_type = $3, question_content = $4, answer_type = $5,
+				answer_content = $6, updated_at = $7
+				WHERE id = $8 AND quest_id = $9 AND deleted_at IS NULL
+				RETURNING *`,
+				step.Sort, step.Description, step.QuestionType, step.QuestionContent, step.AnswerType, step.AnswerContent, now,
+				step.ID, questId)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrStepNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+			}
+		} else {
+			err = tx.GetContext(ctx, &s,
+				`INSERT INTO
+				steps(quest_id, sort, description, question_type, question_content, answer_type, answer_content, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+				RETURNING *`,
+				questId, step.Sort, step.Description, step.QuestionType, step.QuestionContent, step.AnswerType, step.AnswerContent,
+				now)
+		}
+		if err = checkWriteError(err); err != nil {
+			return nil, err
+		}
+
+		saved = append(saved, s)
+	}
+
+	sortSteps(saved)
+	return saved, nil
+}
+
+func sortSteps(steps []model.Step) {
+	sort.Slice(steps, func(i, j int) bool {
+		return *steps[i].Sort < *steps[j].Sort
+	})
+}
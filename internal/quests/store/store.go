@@ -29,9 +29,14 @@ const (
 	// ErrEmptyPassword is returned when the password is empty.
 	ErrEmptyPassword = errors.Error("empty_password: password is empty")
 	// ErrInvalidID si returned when the ID is not a valid UUID or is empty.
-	ErrInvalidID        = errors.Error("invalid_id: id is invalid")
-	ErrQuestAlreadySent = errors.Error("Нельзя удалить квест, отправленый другу!")
-	ErrQuestNotDeleted  = errors.Error("quest not deleted")
+	ErrInvalidID       = errors.Error("invalid_id: id is invalid")
+	ErrQuestNotDeleted = errors.Error("quest not deleted")
+	// ErrDuplicateStepSort is returned when two steps of a quest have the same sort.
+	ErrDuplicateStepSort = errors.Error("duplicate_step_sort: steps of a quest must have different sort")
+	// ErrStepNotFound is returned when a step doesn't belong to the quest.
+	ErrStepNotFound = errors.Error("step_not_found: step not found in the quest")
+	// ErrInvalidStepOrder is returned when the new order doesn't list every step of the quest exactly once.
+	ErrInvalidStepOrder = errors.Error("invalid_step_order: order must list every step of the quest exactly once")
 )
 
 const (
@@ -51,6 +56,7 @@ type DB interface {
 	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
 }
 
 // Store provides functionality for working with a database.
@@ -67,13 +73,20 @@ func New(db DB) *Store {
 
 // InsertQuest will add a new quest to the database using the provided data.
 func (s *Store) InsertQuest(ctx context.Context, quest *model.QuestWithSteps) (*model.QuestWithSteps, error) {
-	createdQuest, err := s.saveQuest(ctx, quest)
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// A step which can't be stored must not leave the quest behind without steps
+	createdQuest, err := saveQuest(ctx, tx, quest)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(quest.Steps) != 0 {
-		createdQuest, err = s.updateSteps(ctx, createdQuest, quest.Steps)
+		createdQuest, err = insertSteps(ctx, tx, createdQuest, quest.Steps)
 		if err != nil {
 			return nil, err
 		}
@@ -81,14 +94,27 @@ func (s *Store) InsertQuest(ctx context.Context, quest *model.QuestWithSteps) (*
 		quest.Steps = []model.Step{}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, checkWriteError(err)
+	}
+
 	return createdQuest, nil
 }
 
-// GetQuest fetches quest by id
+// GetQuest fetches quest by id, deleted quests aren't found
 func (s *Store) GetQuest(ctx context.Context, id string) (*model.QuestWithSteps, error) {
+	return s.getQuest(ctx, id, false)
+}
+
+// GetQuestWithDeleted fetches quest by id even if it is in the trash, for its recipients who can still play it
+func (s *Store) GetQuestWithDeleted(ctx context.Context, id string) (*model.QuestWithSteps, error) {
+	return s.getQuest(ctx, id, true)
+}
+
+func (s *Store) getQuest(ctx context.Context, id string, withDeleted bool) (*model.QuestWithSteps, error) {
 	var q model.QuestWithSteps
 
-	if err := s.db.GetContext(ctx, &q, "SELECT * FROM quests WHERE id = $1", id); err != nil {
+	if err := s.db.GetContext(ctx, &q, "SELECT * FROM quests WHERE id = $1 AND ($2 OR deleted_at IS NULL)", id, withDeleted); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.ErrNotFound.Wrap(err)
 		}
@@ -102,7 +128,7 @@ func (s *Store) GetQuest(ctx context.Context, id string) (*model.QuestWithSteps,
 		return nil, errors.ErrUnknown.Wrap(err)
 	}
 
-	res, err := s.db.QueryxContext(ctx, `SELECT * FROM steps WHERE quest_id = $1`, id)
+	res, err := s.db.QueryxContext(ctx, `SELECT * FROM steps WHERE quest_id = $1 AND deleted_at IS NULL`, id)
 
 	if err = checkWriteError(err); err != nil {
 		return nil, err
@@ -140,9 +166,9 @@ func (s *Store) GetRecipients(ctx context.Context, ownerId string, questId strin
 	return r, nil
 }
 
-// GetQuestsByUser will get quests created by user
-func (s *Store) GetQuestsByUser(ctx context.Context, uuid string, offset int, limit int) ([]model.Quest, *model.Meta, error) {
-	rows, err := s.db.QueryxContext(ctx, "SELECT * FROM quests WHERE owner=$1 ORDER BY created_at ASC LIMIT $2 OFFSET $3", uuid, limit, offset)
+// GetQuestsByUser will get quests created by user, only the ones in the state unless it is empty
+func (s *Store) GetQuestsByUser(ctx context.Context, uuid string, state model.State, offset int, limit int) ([]model.Quest, *model.Meta, error) {
+	rows, err := s.db.QueryxContext(ctx, "SELECT * FROM quests WHERE owner=$1 AND deleted_at IS NULL AND ($4 = '' OR state = $4) ORDER BY created_at ASC LIMIT $2 OFFSET $3", uuid, limit, offset, state)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil, errors.ErrNotFound.Wrap(err)
@@ -185,11 +211,11 @@ func (s *Store) GetQuestsByUser(ctx context.Context, uuid string, offset int, li
 
 	defer res.Close()
 
-	const countQuery = `SELECT count(*) as total_count FROM quests q WHERE owner=$1`
+	const countQuery = `SELECT count(*) as total_count FROM quests q WHERE owner=$1 AND deleted_at IS NULL AND ($2 = '' OR state = $2)`
 
 	var meta model.Meta
 
-	err = s.db.GetContext(ctx, &meta, countQuery, uuid)
+	err = s.db.GetContext(ctx, &meta, countQuery, uuid, state)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -202,58 +228,71 @@ func (s *Store) GetQuestsByUser(ctx context.Context, uuid string, offset int, li
 	return quests, &meta, nil
 }
 
+// UpdateQuest updates the quest and diffs its steps by ID: steps with an ID are updated, steps without one are added
+// and saved steps left out are moved to the trash, so steps keep their identity across edits.
 func (s *Store) UpdateQuest(ctx context.Context, quest *model.QuestWithSteps) (*model.QuestWithSteps, error) {
 	quest.UpdatedAt = timeNow()
 
-	res, err := s.db.NamedQueryContext(ctx, `UPDATE quests SET "name" = :name, description = :description, 
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	res, err := sqlx.NamedQueryContext(ctx, tx, `UPDATE quests SET "name" = :name, description = :description, 
                   theme = :theme, final_message = :final_message, rewards = :rewards, updated_at = :updated_at 
-			WHERE id = :id RETURNING *`, quest)
+			WHERE id = :id AND deleted_at IS NULL RETURNING *`, quest)
 	if err = checkWriteError(err); err != nil {
 		return nil, err
 	}
 	if !res.Next() {
+		res.Close()
 		return nil, errors.ErrNotFound
 	}
-	defer res.Close()
 
 	updatedQuest := &model.QuestWithSteps{}
 
-	if err := res.StructScan(&updatedQuest); err != nil {
+	err = res.StructScan(&updatedQuest)
+	res.Close()
+	if err != nil {
 		return nil, errors.ErrUnknown.Wrap(err)
 	}
 
-	// Delete saved steps
-	_, err = s.db.ExecContext(ctx, "DELETE FROM steps WHERE quest_id = $1", updatedQuest.ID)
+	updatedQuest.Steps, err = syncSteps(ctx, tx, *updatedQuest.ID, quest.Steps, quest.UpdatedAt)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.ErrNotFound.Wrap(err)
-		}
-		return nil, errors.ErrUnknown.Wrap(err)
+		return nil, err
 	}
 
-	// Update steps if there are any
-	if len(quest.Steps) == 0 {
-		return updatedQuest, nil
+	if err := tx.Commit(); err != nil {
+		return nil, checkWriteError(err)
 	}
 
-	// Remove IDs from steps if there were any
-	for i, step := range quest.Steps {
-		emptyId := ""
-		if step.ID != &emptyId {
-			quest.Steps[i].ID = &emptyId
-		}
-	}
+	return updatedQuest, nil
+}
 
-	updatedQuest, err = s.updateSteps(ctx, updatedQuest, quest.Steps)
+// SetState will move the quest to the state, the first publication sets published_at.
+func (s *Store) SetState(ctx context.Context, id string, state model.State) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE quests SET state = $1,
+                  published_at = CASE WHEN $1 = 'published' THEN COALESCE(published_at, $2) ELSE published_at END,
+                  updated_at = $2
+			WHERE id = $3 AND deleted_at IS NULL`, state, timeNow(), id)
 	if err = checkWriteError(err); err != nil {
-		return nil, err
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return errors.ErrNotFound
 	}
 
-	return updatedQuest, nil
+	return nil
 }
 
 func (s *Store) CreateAssignment(ctx context.Context, request model.SendQuestRequest) error {
-	res, err := s.db.NamedQueryContext(ctx, `INSERT INTO quest_to_email(quest_id, email, name) VALUES (:quest_id, :email, :name)`, request)
+	res, err := s.db.NamedQueryContext(ctx, `INSERT INTO quest_to_email(quest_id, email, name, version_id) VALUES (:quest_id, :email, :name, :version_id)`, request)
 	if err = checkWriteError(err); err != nil {
 		return err
 	}
@@ -280,29 +319,58 @@ func (s *Store) GetAssignment(ctx context.Context, questId string, userId string
 	return &a, nil
 }
 
-func (s *Store) UpdateAssignment(ctx context.Context, questId string, userId *string, currentStep int, status model.Status) error {
-	res, err := s.db.QueryxContext(ctx,
+// GetAssignmentByEmail fetches the quest sent to the email, used for guests playing without an account.
+func (s *Store) GetAssignmentByEmail(ctx context.Context, questId string, email string) (*model.Assignment, error) {
+	var a model.Assignment
+
+	if err := s.db.GetContext(ctx, &a, "SELECT * FROM quest_to_email WHERE quest_id = $1 AND email = $2", questId, email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+		return nil, checkWriteError(err)
+	}
+	return &a, nil
+}
+
+func (s *Store) UpdateAssignment(ctx context.Context, questId string, email string, currentStep int, status model.Status) error {
+	_, err := s.db.ExecContext(ctx,
 		`UPDATE quest_to_email
 				SET "status" = $1,
 				    "current_step" = $2
-				WHERE email = (select email from users where id = $4)
-				AND quest_id = $3`, status, currentStep, questId, userId)
-	defer res.Close()
+				WHERE email = $4
+				AND quest_id = $3`, status, currentStep, questId, email)
 	return checkWriteError(err)
 }
 
+// ReassignAssignment moves the quest sent to one email, together with the progress on it, to another email.
+func (s *Store) ReassignAssignment(ctx context.Context, questId string, fromEmail string, toEmail string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE quest_to_email SET email = $1 WHERE quest_id = $2 AND email = $3`, toEmail, questId, fromEmail)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteQuest moves the quest to the trash. Its recipients can still play it, it is purged once the trash
+// retention passed unless it was sent.
 func (s *Store) DeleteQuest(ctx context.Context, id string) error {
 	userId := ctx.Value(http.ContextUserIdKey).(string)
-	res, err := s.db.ExecContext(ctx, "DELETE FROM quests WHERE id = $1 AND owner = $2", id, userId)
+	res, err := s.db.ExecContext(ctx, "UPDATE quests SET deleted_at = $3 WHERE id = $1 AND owner = $2 AND deleted_at IS NULL", id, userId, timeNow())
 	if err != nil {
 		var pqErr *pq.Error
 		if errors.As(err, &pqErr) {
 			if pqErr.Code.Name() == pqErrInvalidTextRepresentation && strings.Contains(pqErr.Error(), "uuid") {
 				return ErrInvalidID.Wrap(errors.ErrValidation.Wrap(err))
 			}
-			if strings.Contains(pqErr.Error(), "quest_to_email") {
-				return ErrQuestAlreadySent.Wrap(errors.ErrValidation.Wrap(err))
-			}
 		}
 
 		return errors.ErrUnknown.Wrap(err)
@@ -318,25 +386,48 @@ func (s *Store) DeleteQuest(ctx context.Context, id string) error {
 	return nil
 }
 
+// GetQuestIDsByOwner returns ids of all quests created by the user.
+func (s *Store) GetQuestIDsByOwner(ctx context.Context, ownerId string) ([]string, error) {
+	ids := []string{}
+	if err := s.db.SelectContext(ctx, &ids, "SELECT id FROM quests WHERE owner = $1 ORDER BY created_at ASC", ownerId); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	return ids, nil
+}
+
+// GetAssignmentsByEmail returns all quests sent to the email together with the progress on them.
+func (s *Store) GetAssignmentsByEmail(ctx context.Context, email string) ([]model.Assignment, error) {
+	a := []model.Assignment{}
+	if err := s.db.SelectContext(ctx, &a, "SELECT * FROM quest_to_email WHERE email = $1", email); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	return a, nil
+}
+
 func (s *Store) GetQuestsAvailable(ctx context.Context, email string, offset int, limit int, finished bool) ([]model.QuestAvailable, *model.Meta, error) {
 	statusWhere := fmt.Sprintf("qe.status IN ('%s','%s')", model.StatusNotStarted, model.StatusInProgress)
 	if finished {
 		statusWhere = fmt.Sprintf("qe.status = '%s'", model.StatusFinished)
 	}
+	// Quests sent with a version are shown as they were sent
 	query := fmt.Sprintf(`SELECT qe.quest_id,
-       q.name as quest_name,
-       q.description as quest_description,
-       q.theme as quest_theme,
+       CASE WHEN v.id IS NULL THEN q.name ELSE v.snapshot ->> 'name' END               as quest_name,
+       CASE WHEN v.id IS NULL THEN q.description ELSE v.snapshot ->> 'description' END as quest_description,
+       CASE WHEN v.id IS NULL THEN q.theme ELSE v.snapshot ->> 'theme' END             as quest_theme,
        qe.status,
        qe.current_step as steps_current,
-       CASE when s.steps_count is NULL THEN 0 ELSE s.steps_count END AS steps_count,
+       CASE
+           WHEN v.id IS NOT NULL THEN jsonb_array_length(v.snapshot -> 'steps')
+           WHEN s.steps_count is NULL THEN 0
+           ELSE s.steps_count END AS steps_count,
        u.id                                   as "owner.id",
        concat(u.first_name, ' ', u.last_name) as "owner.name"
 FROM quests q
          JOIN quest_to_email qe ON qe.quest_id = q.id
          JOIN users u ON q.owner = u.id
+         LEFT JOIN quest_versions v ON v.id = qe.version_id
          FULL OUTER JOIN (SELECT DISTINCT steps.quest_id, COUNT(*) AS steps_count
-                           FROM steps GROUP BY steps.quest_id) as s ON qe.quest_id = s.quest_id
+                           FROM steps WHERE steps.deleted_at IS NULL GROUP BY steps.quest_id) as s ON qe.quest_id = s.quest_id
 WHERE qe.email = $1 AND %s
 ORDER BY q.created_at ASC
 OFFSET $2 LIMIT $3`, statusWhere)
@@ -372,16 +463,61 @@ OFFSET $2 LIMIT $3`, statusWhere)
 	return quests, &meta, nil
 }
 
+// GetPublishedQuests returns the published public quests of the owner, newest first.
+func (s *Store) GetPublishedQuests(ctx context.Context, ownerId string, offset int, limit int) ([]model.QuestSummary, *model.Meta, error) {
+	quests := []model.QuestSummary{}
+
+	err := s.db.SelectContext(ctx, &quests,
+		`SELECT q.id, q.name, q.description, q.theme, q.published_at, q.slug,
+       (SELECT count(*) FROM steps s WHERE s.quest_id = q.id AND s.deleted_at IS NULL) AS steps_count
+FROM quests q
+WHERE q.owner = $1 AND q.state = $4 AND q.visibility = $5 AND q.deleted_at IS NULL
+ORDER BY q.published_at DESC
+OFFSET $2 LIMIT $3`, ownerId, offset, limit, model.StatePublished, model.VisibilityPublic)
+	if err = checkWriteError(err); err != nil {
+		return nil, nil, err
+	}
+
+	var meta model.Meta
+
+	err = s.db.GetContext(ctx, &meta,
+		`SELECT count(*) as total_count FROM quests WHERE owner = $1 AND state = $2 AND visibility = $3 AND deleted_at IS NULL`,
+		ownerId, model.StatePublished, model.VisibilityPublic)
+	if err = checkWriteError(err); err != nil {
+		return nil, nil, err
+	}
+
+	return quests, &meta, nil
+}
+
+// GetAuthorStats counts the published public quests of the owner and how often their quests were sent and finished.
+func (s *Store) GetAuthorStats(ctx context.Context, ownerId string) (*model.AuthorStats, error) {
+	var stats model.AuthorStats
+
+	err := s.db.GetContext(ctx, &stats,
+		`SELECT (SELECT count(*) FROM quests WHERE owner = $1 AND state = $3 AND visibility = $4 AND deleted_at IS NULL) AS quests_published,
+       count(qe.quest_id)                                                                  AS times_sent,
+       count(qe.quest_id) FILTER (WHERE qe.status = $2)                                    AS times_finished
+FROM quest_to_email qe
+         JOIN quests q ON q.id = qe.quest_id
+WHERE q.owner = $1`, ownerId, model.StatusFinished, model.StatePublished, model.VisibilityPublic)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
 // Private methods
 
-func (s *Store) updateSteps(ctx context.Context, quest *model.QuestWithSteps, steps []model.Step) (*model.QuestWithSteps, error) {
+func insertSteps(ctx context.Context, tx *sqlx.Tx, quest *model.QuestWithSteps, steps []model.Step) (*model.QuestWithSteps, error) {
 	for i := range steps {
 		steps[i].QuestId = quest.ID
 		steps[i].CreatedAt = quest.UpdatedAt
 		steps[i].UpdatedAt = quest.UpdatedAt
 	}
 
-	res, err := s.db.NamedQueryContext(ctx, `INSERT INTO
+	res, err := sqlx.NamedQueryContext(ctx, tx, `INSERT INTO
 			steps(quest_id, sort,description,question_type,question_content,answer_type,answer_content,created_at,updated_at)
 			VALUES (:quest_id, :sort,:description,:question_type,:question_content,:answer_type,:answer_content,:created_at,:updated_at)
 			RETURNING *`, steps)
@@ -398,16 +534,19 @@ func (s *Store) updateSteps(ctx context.Context, quest *model.QuestWithSteps, st
 		}
 		quest.Steps = append(quest.Steps, step)
 	}
+	if err := res.Err(); err != nil {
+		return nil, checkWriteError(err)
+	}
 
 	defer res.Close()
 	return quest, nil
 }
 
-func (s *Store) saveQuest(ctx context.Context, quest *model.QuestWithSteps) (*model.QuestWithSteps, error) {
+func saveQuest(ctx context.Context, tx *sqlx.Tx, quest *model.QuestWithSteps) (*model.QuestWithSteps, error) {
 	quest.CreatedAt = timeNow()
 	quest.UpdatedAt = quest.CreatedAt
 
-	res, err := s.db.NamedQueryContext(ctx,
+	res, err := sqlx.NamedQueryContext(ctx, tx,
 		`INSERT INTO 
 		quests("name",description,"owner",theme,final_message, rewards,created_at,updated_at) 
 		VALUES (:name,:description,:owner,:theme,:final_message, :rewards, :created_at, :updated_at) 
@@ -462,6 +601,11 @@ func checkWriteError(err error) error {
 			default:
 				return errors.ErrValidation.Wrap(err)
 			}
+		case "exclusion_violation":
+			if strings.Contains(pqErr.Error(), "steps_sort_unique") {
+				return ErrDuplicateStepSort.Wrap(errors.ErrValidation.Wrap(err))
+			}
+			return errors.ErrValidation.Wrap(err)
 		case "unique_violation":
 			if strings.Contains(pqErr.Error(), "quest_id_email_unique") {
 				return ErrQuestAlreadySentToEmail.Wrap(errors.ErrValidation.Wrap(err))
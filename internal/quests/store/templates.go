@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+)
+
+// ErrTemplateNotFound is returned when there is no template with the ID.
+const ErrTemplateNotFound = errors.Error("template_not_found: template not found")
+
+// InsertTemplate will add a new template to the library.
+func (s *Store) InsertTemplate(ctx context.Context, t *model.Template) (*model.Template, error) {
+	t.CreatedAt = timeNow()
+	t.UpdatedAt = t.CreatedAt
+
+	var created model.Template
+
+	err := s.db.GetContext(ctx, &created,
+		`INSERT INTO quest_templates(theme, "name", description, variables, content, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING *, jsonb_array_length(content -> 'steps') AS steps_count`,
+		t.Theme, t.Name, t.Description, t.Variables, t.Quest, t.CreatedAt)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// GetTemplates returns the templates of the theme, or of every theme when it is empty, without their quests.
+func (s *Store) GetTemplates(ctx context.Context, theme model.Theme) ([]model.Template, error) {
+	templates := []model.Template{}
+
+	err := s.db.SelectContext(ctx, &templates,
+		`SELECT id, theme, "name", description, variables, created_at, updated_at,
+       jsonb_array_length(content -> 'steps') AS steps_count
+FROM quest_templates
+WHERE $1 = '' OR theme = $1
+ORDER BY theme, "name"`, theme)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// GetTemplate returns the template with its quest.
+func (s *Store) GetTemplate(ctx context.Context, id string) (*model.Template, error) {
+	var t model.Template
+
+	err := s.db.GetContext(ctx, &t,
+		`SELECT *, jsonb_array_length(content -> 'steps') AS steps_count FROM quest_templates WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTemplateNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &t, nil
+}
+
+// UpdateTemplate will replace the template.
+func (s *Store) UpdateTemplate(ctx context.Context, t *model.Template) (*model.Template, error) {
+	t.UpdatedAt = timeNow()
+
+	var updated model.Template
+
+	err := s.db.GetContext(ctx, &updated,
+		`UPDATE quest_templates SET theme = $1, "name" = $2, description = $3, variables = $4, content = $5, updated_at = $6
+		WHERE id = $7
+		RETURNING *, jsonb_array_length(content -> 'steps') AS steps_count`,
+		t.Theme, t.Name, t.Description, t.Variables, t.Quest, t.UpdatedAt, t.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTemplateNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &updated, nil
+}
+
+// DeleteTemplate will remove the template from the library, quests created from it are kept.
+func (s *Store) DeleteTemplate(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM quest_templates WHERE id = $1`, id)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return ErrTemplateNotFound.Wrap(errors.ErrNotFound)
+	}
+
+	return nil
+}
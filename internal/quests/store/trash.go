@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+)
+
+// GetTrash returns the deleted quests of the owner and the deleted steps of the quests which aren't deleted,
+// the latest deleted first.
+func (s *Store) GetTrash(ctx context.Context, ownerId string) (*model.Trash, error) {
+	trash := model.Trash{Quests: []model.Quest{}, Steps: []model.Step{}}
+
+	err := s.db.SelectContext(ctx, &trash.Quests,
+		`SELECT * FROM quests WHERE owner = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC`, ownerId)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	err = s.db.SelectContext(ctx, &trash.Steps,
+		`SELECT s.* FROM steps s JOIN quests q ON q.id = s.quest_id
+		WHERE q.owner = $1 AND q.deleted_at IS NULL AND s.deleted_at IS NOT NULL
+		ORDER BY s.deleted_at DESC`, ownerId)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return &trash, nil
+}
+
+// RestoreQuest will take the quest of the owner out of the trash.
+func (s *Store) RestoreQuest(ctx context.Context, id string, ownerId string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE quests SET deleted_at = NULL WHERE id = $1 AND owner = $2 AND deleted_at IS NOT NULL`, id, ownerId)
+	if err = checkWriteError(err); err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	if rows != 1 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// RestoreStep will take the step out of the trash. The step keeps its sort unless another step took it meanwhile
+// or atEnd is set, then it is appended after the last step.
+func (s *Store) RestoreStep(ctx context.Context, questId string, stepId string, atEnd bool) (*model.Step, error) {
+	var step model.Step
+
+	err := s.db.GetContext(ctx, &step,
+		`UPDATE steps s SET deleted_at = NULL, updated_at = $4,
+                 sort = CASE
+                            WHEN $3 OR EXISTS(SELECT 1 FROM steps o WHERE o.quest_id = s.quest_id AND o.sort = s.sort AND o.deleted_at IS NULL)
+                                THEN (SELECT COALESCE(max(sort), 0) + 1 FROM steps WHERE quest_id = s.quest_id AND deleted_at IS NULL)
+                            ELSE s.sort END
+		WHERE s.id = $1 AND s.quest_id = $2 AND s.deleted_at IS NOT NULL
+		RETURNING s.*`, stepId, questId, atEnd, timeNow())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStepNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &step, nil
+}
+
+// PurgeTrash will delete the steps and quests moved to the trash before the time for good. Quests which were sent
+// stay in the trash, so their recipients can still play them. Returns ids of the deleted quests.
+func (s *Store) PurgeTrash(ctx context.Context, before time.Time) ([]string, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM steps WHERE deleted_at < $1`, before); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	ids := []string{}
+	err = tx.SelectContext(ctx, &ids,
+		`DELETE FROM quests q
+		WHERE q.deleted_at < $1 AND NOT EXISTS(SELECT 1 FROM quest_to_email qe WHERE qe.quest_id = q.id)
+		RETURNING q.id`, before)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return ids, nil
+}
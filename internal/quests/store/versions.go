@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+)
+
+// ErrVersionNotFound is returned when the quest has no such version.
+const ErrVersionNotFound = errors.Error("version_not_found: version not found")
+
+// SaveVersion will store the snapshot as the next version of the quest, unless the latest version has the same
+// content hash, in which case the latest version is returned.
+func (s *Store) SaveVersion(ctx context.Context, snapshot *model.Snapshot, contentHash string) (*model.QuestVersion, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Sending the quest twice at once must not take the same version number
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM quests WHERE id = $1 FOR UPDATE`, snapshot.ID); err != nil {
+		return nil, checkWriteError(err)
+	}
+
+	latest, err := latestVersion(ctx, tx, *snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.ContentHash == contentHash {
+		return latest, nil
+	}
+
+	version := model.QuestVersion{
+		QuestId:     *snapshot.ID,
+		Version:     1,
+		Snapshot:    snapshot,
+		ContentHash: contentHash,
+		CreatedAt:   timeNow(),
+	}
+	if latest != nil {
+		version.Version = latest.Version + 1
+	}
+
+	err = tx.GetContext(ctx, &version.ID,
+		`INSERT INTO quest_versions(quest_id, version, snapshot, content_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		version.QuestId, version.Version, version.Snapshot, version.ContentHash, version.CreatedAt)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, checkWriteError(err)
+	}
+
+	return &version, nil
+}
+
+// GetVersions returns the versions of the quest without their snapshots, with the number of recipients on each.
+func (s *Store) GetVersions(ctx context.Context, questId string) ([]model.QuestVersion, error) {
+	versions := []model.QuestVersion{}
+
+	err := s.db.SelectContext(ctx, &versions,
+		`SELECT v.id, v.quest_id, v.version, v.content_hash, v.created_at,
+       (SELECT count(*) FROM quest_to_email qe WHERE qe.version_id = v.id) AS recipients
+FROM quest_versions v
+WHERE v.quest_id = $1
+ORDER BY v.version DESC`, questId)
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetVersion returns a version of the quest by its number.
+func (s *Store) GetVersion(ctx context.Context, questId string, version int) (*model.QuestVersion, error) {
+	var v model.QuestVersion
+
+	err := s.db.GetContext(ctx, &v,
+		`SELECT v.*, (SELECT count(*) FROM quest_to_email qe WHERE qe.version_id = v.id) AS recipients
+FROM quest_versions v
+WHERE v.quest_id = $1 AND v.version = $2`, questId, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVersionNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &v, nil
+}
+
+// GetVersionByID returns a version by its ID, used to play the version an assignment is pinned to.
+func (s *Store) GetVersionByID(ctx context.Context, id string) (*model.QuestVersion, error) {
+	var v model.QuestVersion
+
+	if err := s.db.GetContext(ctx, &v, `SELECT * FROM quest_versions WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVersionNotFound.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &v, nil
+}
+
+// GetQuestAssignments returns the assignments of the quest, only the given emails unless the list is empty.
+func (s *Store) GetQuestAssignments(ctx context.Context, questId string, emails []string) ([]model.Assignment, error) {
+	a := []model.Assignment{}
+
+	err := s.db.SelectContext(ctx, &a,
+		`SELECT * FROM quest_to_email WHERE quest_id = $1 AND (cardinality($2::varchar[]) = 0 OR email = ANY($2))
+		ORDER BY email`, questId, pq.Array(emails))
+	if err = checkWriteError(err); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// MigrateAssignments will move the assignments to their version and step in one transaction.
+func (s *Store) MigrateAssignments(ctx context.Context, assignments []model.Assignment) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, a := range assignments {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE quest_to_email SET version_id = $1, current_step = $2 WHERE quest_id = $3 AND email = $4`,
+			a.VersionId, a.CurrentStep, a.QuestId, a.Email)
+		if err = checkWriteError(err); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return checkWriteError(err)
+	}
+
+	return nil
+}
+
+func latestVersion(ctx context.Context, tx *sqlx.Tx, questId string) (*model.QuestVersion, error) {
+	var v model.QuestVersion
+
+	err := tx.GetContext(ctx, &v,
+		`SELECT id, quest_id, version, content_hash, created_at FROM quest_versions
+		WHERE quest_id = $1 ORDER BY version DESC LIMIT 1`, questId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &v, nil
+}
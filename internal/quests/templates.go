@@ -0,0 +1,85 @@
+package quests
+
+import (
+	"context"
+	"strings"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/events"
+	"github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"github.com/superhorsy/quest-app-backend/internal/transport/http"
+)
+
+// GetTemplates returns the templates of the theme, or of every theme when it is empty.
+func (q *Quests) GetTemplates(ctx context.Context, theme model.Theme) ([]model.Template, error) {
+	return q.store.GetTemplates(ctx, theme)
+}
+
+// GetTemplate returns the template with its quest.
+func (q *Quests) GetTemplate(ctx context.Context, id string) (*model.Template, error) {
+	return q.store.GetTemplate(ctx, id)
+}
+
+// CreateTemplate adds a template to the library, every variable used in its texts must be declared.
+func (q *Quests) CreateTemplate(ctx context.Context, t *model.Template) (*model.Template, error) {
+	if err := prepareTemplate(t); err != nil {
+		return nil, err
+	}
+	return q.store.InsertTemplate(ctx, t)
+}
+
+// UpdateTemplate replaces a template of the library, quests created from it don't change.
+func (q *Quests) UpdateTemplate(ctx context.Context, t *model.Template) (*model.Template, error) {
+	if err := prepareTemplate(t); err != nil {
+		return nil, err
+	}
+	return q.store.UpdateTemplate(ctx, t)
+}
+
+// DeleteTemplate removes a template from the library.
+func (q *Quests) DeleteTemplate(ctx context.Context, id string) error {
+	return q.store.DeleteTemplate(ctx, id)
+}
+
+// CreateQuestFromTemplate creates a new draft of the user from the template, with the values substituted for
+// its variables.
+func (q *Quests) CreateQuestFromTemplate(ctx context.Context, templateId string, values map[string]string) (*model.QuestWithSteps, error) {
+	t, err := q.store.GetTemplate(ctx, templateId)
+	if err != nil {
+		return nil, err
+	}
+
+	quest, missing := t.NewQuest(values)
+	if len(missing) != 0 {
+		return nil, errors.Error("missing_variables: no values for " + strings.Join(missing, ", ")).Wrap(errors.ErrValidation)
+	}
+	userId := ctx.Value(http.ContextUserIdKey).(string)
+	quest.Owner = &userId
+
+	createdQuest, err := q.store.InsertQuest(ctx, quest)
+	if err != nil {
+		return nil, err
+	}
+
+	q.events.Produce(ctx, events.TopicQuests, events.QuestEvent{
+		EventType: events.EventTypeQuestCreated,
+		ID:        *createdQuest.ID,
+		Quest:     createdQuest,
+	})
+
+	return createdQuest, nil
+}
+
+func prepareTemplate(t *model.Template) error {
+	if t.Variables == nil {
+		t.Variables = model.TemplateVariables{}
+	}
+	if t.Quest != nil && t.Quest.Steps == nil {
+		t.Quest.Steps = []model.Step{}
+	}
+
+	if problems := t.Validate(); len(problems) != 0 {
+		return errors.Error("template_invalid: " + strings.Join(problems, "; ")).Wrap(errors.ErrValidation)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	authModel "github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"github.com/superhorsy/quest-app-backend/internal/users/model"
+	"go.uber.org/zap"
+)
+
+// ErrCannotDisableSelf is returned when an admin tries to disable their own account.
+const ErrCannotDisableSelf = errors.Error("cannot_disable_self: you can't disable your own account")
+
+type SetRoleRequest struct {
+	Role model.Role `json:"role"`
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var limit, offset int64 = 50, 0
+	var err error
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.ParseInt(v, 10, 64); err != nil || limit < 1 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid limit")))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.ParseInt(v, 10, 64); err != nil || offset < 0 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid offset")))
+			return
+		}
+	}
+
+	users, err := s.users.ListUsers(ctx, offset, limit)
+	if err != nil {
+		logging.From(ctx).Error("failed to list users", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, users)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.users.GetUser(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, u)
+}
+
+// setUserRole changes the role of the user and signs them out of every session, so tokens carrying the old role
+// stop working right away.
+func (s *Server) setUserRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, SetRoleRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	u, err := s.users.SetRole(ctx, id, req.Role)
+	if err != nil {
+		logging.From(ctx).Error("failed to set user role", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	if err := s.auth.RevokeUserSessions(ctx, id); err != nil {
+		logging.From(ctx).Error("failed to revoke sessions", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, u)
+}
+
+func (s *Server) disableUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+	if id == ctx.Value(ContextUserIdKey).(string) {
+		handleError(ctx, w, ErrCannotDisableSelf.Wrap(errors.ErrValidation))
+		return
+	}
+
+	u, err := s.users.SetDisabled(ctx, id, true)
+	if err != nil {
+		logging.From(ctx).Error("failed to disable user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	if err := s.auth.RevokeUserSessions(ctx, id); err != nil {
+		logging.From(ctx).Error("failed to revoke sessions", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, u)
+}
+
+func (s *Server) enableUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.users.SetDisabled(ctx, mux.Vars(r)["id"], false)
+	if err != nil {
+		logging.From(ctx).Error("failed to enable user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, u)
+}
+
+func (s *Server) inspectQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quest, err := s.quests.InspectQuest(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to fetch quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+func (s *Server) getLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	filter := authModel.LoginAttemptFilter{
+		Email: query.Get("email"),
+		IP:    query.Get("ip"),
+		Limit: 50,
+	}
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.ParseInt(v, 10, 64); err != nil || filter.Limit < 1 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid limit")))
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.ParseInt(v, 10, 64); err != nil || filter.Offset < 0 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid offset")))
+			return
+		}
+	}
+
+	attempts, err := s.auth.GetLoginAttempts(ctx, filter)
+	if err != nil {
+		logging.From(ctx).Error("failed to get login attempts", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, attempts)
+}
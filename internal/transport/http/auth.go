@@ -1,18 +1,30 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
 	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
 	"github.com/superhorsy/quest-app-backend/internal/core/logging"
 	"github.com/superhorsy/quest-app-backend/internal/users/model"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
+	"time"
 )
 
+// ErrInvalidCredentials is returned on a failed login, the same for an unknown email and a wrong password.
+const ErrInvalidCredentials = errors.Error("invalid_credentials: wrong email or password")
+
+// ErrEmailAlreadyVerified is returned when a verification email is requested for an already verified email.
+const ErrEmailAlreadyVerified = errors.Error("email_already_verified: email is already verified")
+
+// maxEmailLength is the longest email an account can have.
+const maxEmailLength = 255
+
 type LoginForm struct {
 	Password string `json:"password"`
 	Email    string `json:"email"`
@@ -26,6 +38,23 @@ type RegistrationRequest struct {
 	Email     string `json:"email"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
 type Validation struct {
 	Value string
 	Valid string
@@ -51,10 +80,6 @@ func validation(values []Validation) error {
 			if !email.MatchString(values[i].Value) {
 				return values[i].Error
 			}
-		case "password":
-			if len(values[i].Value) < 5 {
-				return values[i].Error
-			}
 		}
 	}
 	return nil
@@ -81,7 +106,6 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 		[]Validation{
 			//{Value: f.Nickname, Valid: "username", Error: errors.New("Username can contain only numbers and english letters")},
 			{Value: f.Email, Valid: "email", Error: errors.New("Invalid email")},
-			//{Value: f.Password, Valid: "password", Error: errors.New("Password should be at least 5 letters long")},
 		})
 	if err != nil {
 		logging.From(ctx).Error("validation failed", zap.Error(err))
@@ -104,14 +128,19 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := helpers.CreateJwtToken(*createdUser.ID)
+	tokens, err := s.auth.IssueTokens(ctx, *createdUser.ID, clientInfo(r))
 	if err != nil {
 		logging.From(ctx).Error("failed to create token", zap.Error(err))
 		handleError(ctx, w, err)
 		return
 	}
 
-	handleResponse(ctx, w, createdUser, *token)
+	// The account is usable right away, the verification only guards quests sent to the email
+	if err := s.sendEmailVerification(ctx, createdUser); err != nil {
+		logging.From(ctx).Error("failed to send email verification", zap.Error(err))
+	}
+
+	handleTokenResponse(ctx, w, createdUser, tokens)
 }
 
 func (s *Server) login(w http.ResponseWriter, r *http.Request) {
@@ -131,33 +160,269 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(err))
 		return
 	}
+	// No account has a longer email, and it wouldn't fit in the login attempt history
+	if len(f.Email) > maxEmailLength {
+		handleError(ctx, w, ErrInvalidCredentials.Wrap(errors.ErrUnauthorized))
+		return
+	}
+
+	ip := clientIP(r)
+	if err := s.auth.CheckLoginAllowed(ctx, f.Email, ip); err != nil {
+		logging.From(ctx).Error("login locked out", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
 
 	user, err := s.users.GetUserByEmail(ctx, f.Email)
-	if err != nil {
+	if err != nil && !errors.Is(err, errors.ErrNotFound) {
 		logging.From(ctx).Error("failed to login user", zap.Error(err))
 		handleError(ctx, w, err)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(f.Password))
-	if err == bcrypt.ErrMismatchedHashAndPassword && err != nil {
-		err = errors.New("Wrong password")
+	var userId *string
+	if user != nil {
+		userId = user.ID
+	}
+
+	// Unknown emails never match, but take as long to check as a wrong password
+	success, err := s.users.CheckPassword(ctx, user, f.Password)
+	// Without the attempt recorded failures wouldn't count towards the lockout
+	if err := s.auth.RecordLoginAttempt(ctx, f.Email, userId, ip, success); err != nil {
+		logging.From(ctx).Error("failed to record login attempt", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+	if !success {
 		logging.From(ctx).Error("failed to login user", zap.Error(err))
+		handleError(ctx, w, ErrInvalidCredentials.Wrap(errors.ErrUnauthorized))
+		return
+	}
+
+	s.completeLogin(w, r, user)
+}
+
+func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, RefreshRequest{})
+	if err != nil {
 		handleError(ctx, w, err)
 		return
 	}
+
+	tokens, err := s.auth.Refresh(ctx, req.RefreshToken, clientInfo(r))
 	if err != nil {
-		logging.From(ctx).Error("failed to login user", zap.Error(err))
+		logging.From(ctx).Error("failed to refresh token", zap.Error(err))
 		handleError(ctx, w, err)
 		return
 	}
 
-	token, err := helpers.CreateJwtToken(*user.ID)
+	user, err := s.users.GetUser(ctx, tokens.UserID)
 	if err != nil {
-		logging.From(ctx).Error("failed to create token", zap.Error(err))
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleTokenResponse(ctx, w, user, tokens)
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, RefreshRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	if err := s.auth.Logout(ctx, req.RefreshToken); err != nil {
+		logging.From(ctx).Error("failed to logout", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+func (s *Server) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, ForgotPasswordRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	// The response is the same whether the email is registered or not, so it can't be used to look up accounts
+	success := struct {
+		Success bool `json:"success"`
+	}{Success: true}
+
+	user, err := s.users.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			handleResponse(ctx, w, success)
+			return
+		}
+		logging.From(ctx).Error("failed to find user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	token, err := s.auth.CreatePasswordResetToken(ctx, *user.ID)
+	if err != nil {
+		logging.From(ctx).Error("failed to create password reset token", zap.Error(err))
 		handleError(ctx, w, err)
 		return
 	}
 
-	handleResponse(ctx, w, user, *token)
+	link := fmt.Sprintf("%s/password/reset?token=%s", helpers.GetConfig(ctx).AppURL, url.QueryEscape(token))
+	sendEmail(ctx, *user.Email, "Сброс пароля на Questy.fun", "config/password_reset.gohtml", mailTemplateData{
+		Name: *user.FirstName,
+		URL:  link,
+	})
+
+	handleResponse(ctx, w, success)
+}
+
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, ResetPasswordRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	// Check the policy first, so a rejected password doesn't use up the token
+	if err := s.users.ValidatePassword(req.Password); err != nil {
+		logging.From(ctx).Error("validation failed", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	userId, err := s.auth.UsePasswordResetToken(ctx, req.Token)
+	if err != nil {
+		logging.From(ctx).Error("failed to reset password", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	_, err = s.users.UpdateUser(ctx, &model.UserWithPass{
+		User:     &model.User{ID: &userId},
+		Password: &req.Password,
+	})
+	if err != nil {
+		logging.From(ctx).Error("failed to update password", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	// Sign out everywhere and revoke the tokens, the old password might have been compromised
+	if err := s.auth.RevokeUserSessions(ctx, userId); err != nil {
+		logging.From(ctx).Error("failed to revoke sessions", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+	if err := s.auth.RevokePersonalAccessTokens(ctx, userId); err != nil {
+		logging.From(ctx).Error("failed to revoke personal access tokens", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+func (s *Server) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, VerifyEmailRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	userId, email, err := s.auth.UseEmailVerificationToken(ctx, req.Token)
+	if err != nil {
+		logging.From(ctx).Error("failed to verify email", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	user, err := s.users.VerifyEmail(ctx, userId, email)
+	if err != nil {
+		logging.From(ctx).Error("failed to verify email", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, user)
+}
+
+func (s *Server) resendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userId := ctx.Value(ContextUserIdKey).(string)
+
+	user, err := s.users.GetUser(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	if user.IsVerified() {
+		handleError(ctx, w, ErrEmailAlreadyVerified.Wrap(errors.ErrValidation))
+		return
+	}
+
+	if err := s.sendEmailVerification(ctx, user); err != nil {
+		logging.From(ctx).Error("failed to send email verification", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+func (s *Server) sendEmailVerification(ctx context.Context, user *model.User) error {
+	token, err := s.auth.CreateEmailVerificationToken(ctx, *user.ID, *user.Email)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/email/verify?token=%s", helpers.GetConfig(ctx).AppURL, url.QueryEscape(token))
+	sendEmail(ctx, *user.Email, "Подтверждение email на Questy.fun", "config/email_verification.gohtml", mailTemplateData{
+		Name: *user.FirstName,
+		URL:  link,
+	})
+
+	return nil
+}
+
+// jwksMaxAge lets verifiers cache the keys, a new key is published well before tokens are signed with it.
+const jwksMaxAge = 5 * time.Minute
+
+func (s *Server) getJWKS(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	data, err := json.Marshal(helpers.JWKS())
+	if err != nil {
+		handleError(ctx, w, errors.ErrUnknown.Wrap(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+	if _, err := w.Write(data); err != nil {
+		logging.From(ctx).Error("failed to write response", zap.Error(err))
+	}
 }
@@ -0,0 +1,332 @@
+package http
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	mediaModel "github.com/superhorsy/quest-app-backend/internal/media/model"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// bundleManifest is the name of the manifest in a quest bundle.
+	bundleManifest = "manifest.json"
+	// bundleMaxSize is the largest quest bundle which can be imported.
+	bundleMaxSize = 50 << 20
+	// bundleManifestMaxSize is the largest manifest of a quest bundle.
+	bundleManifestMaxSize = 1 << 20
+	// bundleMaxEntries is how many files a quest bundle can contain.
+	bundleMaxEntries = 200
+	// bundleMaxUncompressedSize is how much the media files of a quest bundle can take once extracted.
+	bundleMaxUncompressedSize = 200 << 20
+)
+
+// invalidBundle returns the error for a bundle which can't be imported, the reason is shown to the user.
+func invalidBundle(reason string) error {
+	return errors.Error("invalid_bundle: " + reason).Wrap(errors.ErrValidation)
+}
+
+// exportQuest downloads the quest as a bundle: a zip archive of manifest.json with the quest and its steps and
+// the uploaded media files the steps link to.
+func (s *Server) exportQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quest, err := s.quests.GetQuest(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to fetch quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	content := questModel.NewSnapshot(quest).QuestWithSteps
+	content.ID, content.Owner = nil, nil
+
+	bundle := questModel.Bundle{
+		Version:    questModel.BundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Quest:      &content,
+		Media:      []questModel.BundleMedia{},
+	}
+
+	records := []*mediaModel.MediaRecord{}
+	bundled := map[string]bool{}
+	for _, step := range content.Steps {
+		if !step.HasMedia() || bundled[*step.QuestionContent] {
+			continue
+		}
+
+		media, err := s.media.GetMediaByLink(ctx, *step.QuestionContent)
+		if errors.Is(err, errors.ErrNotFound) {
+			// Links to files hosted elsewhere are exported as they are
+			continue
+		}
+		if err != nil {
+			logging.From(ctx).Error("failed to fetch media", zap.Error(err))
+			handleError(ctx, w, err)
+			return
+		}
+
+		bundled[*step.QuestionContent] = true
+		records = append(records, media)
+		bundle.Media = append(bundle.Media, questModel.BundleMedia{
+			Link: *step.QuestionContent,
+			Type: string(media.Type),
+			File: path.Join("media", media.Filename),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="quest-%s.zip"`, *quest.ID))
+
+	// Headers are already sent once the archive is being written, so failures can only be logged
+	if err := s.writeBundle(r, w, &bundle, records); err != nil {
+		logging.From(ctx).Error("failed to write quest bundle", zap.Error(err))
+	}
+}
+
+func (s *Server) writeBundle(r *http.Request, w io.Writer, bundle *questModel.Bundle, records []*mediaModel.MediaRecord) error {
+	ctx := r.Context()
+
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create(bundleManifest)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(bundle); err != nil {
+		return err
+	}
+
+	for i, record := range records {
+		src, err := s.media.OpenFile(ctx, record)
+		if err != nil {
+			return err
+		}
+
+		f, err := zw.Create(bundle.Media[i].File)
+		if err == nil {
+			_, err = io.Copy(f, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// importQuest creates a new draft from a bundle made by exportQuest, uploaded as the multipart file "file".
+// The media files of the bundle are uploaded again and the steps link to the uploaded copies.
+func (s *Server) importQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, bundleMaxSize)
+	if err := r.ParseMultipartForm(mediaMaxSize); err != nil {
+		logging.From(ctx).Error("failed to read quest bundle", zap.Error(err))
+		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		logging.From(ctx).Error("failed to read quest bundle", zap.Error(err))
+		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	defer file.Close()
+
+	zr, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		handleError(ctx, w, invalidBundle("not a zip archive"))
+		return
+	}
+
+	bundle, files, err := readBundle(zr)
+	if err != nil {
+		logging.From(ctx).Error("failed to read quest bundle", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	links := map[string]string{}
+	uploaded := map[string]*mediaModel.MediaRecord{}
+	records := []*mediaModel.MediaRecord{}
+	for _, m := range bundle.Media {
+		if record, ok := uploaded[m.File]; ok {
+			links[m.Link] = record.Link
+			continue
+		}
+
+		src, err := files[m.File].Open()
+		if err != nil {
+			s.deleteMedia(ctx, records)
+			handleError(ctx, w, invalidBundle(fmt.Sprintf("%s can't be read", m.File)))
+			return
+		}
+
+		record, err := s.media.UploadFile(ctx, io.LimitReader(src, mediaMaxSize), path.Base(m.File), mediaModel.MediaType(m.Type))
+		src.Close()
+		if err != nil {
+			logging.From(ctx).Error("failed to upload media of quest bundle", zap.Error(err))
+			s.deleteMedia(ctx, records)
+			handleError(ctx, w, err)
+			return
+		}
+		uploaded[m.File] = record
+		records = append(records, record)
+		links[m.Link] = record.Link
+	}
+
+	quest, err := s.quests.ImportQuest(ctx, bundle.Quest, links)
+	if err != nil {
+		logging.From(ctx).Error("failed to import quest", zap.Error(err))
+		s.deleteMedia(ctx, records)
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+// deleteMedia removes the media uploaded for a bundle which couldn't be imported, failures are only logged.
+func (s *Server) deleteMedia(ctx context.Context, records []*mediaModel.MediaRecord) {
+	for _, record := range records {
+		if err := s.media.DeleteMedia(ctx, record); err != nil {
+			logging.From(ctx).Error("failed to delete media of quest bundle", zap.String("id", record.ID), zap.Error(err))
+		}
+	}
+}
+
+// readBundle reads and validates the manifest of the bundle, returning the media files it lists by their path.
+// Media listed more than once under the same link is kept once.
+func readBundle(zr *zip.Reader) (*questModel.Bundle, map[string]*zip.File, error) {
+	if len(zr.File) > bundleMaxEntries {
+		return nil, nil, invalidBundle(fmt.Sprintf("more than %d files", bundleMaxEntries))
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifest, ok := files[bundleManifest]
+	if !ok {
+		return nil, nil, invalidBundle(bundleManifest + " is missing")
+	}
+	if manifest.UncompressedSize64 > bundleManifestMaxSize {
+		return nil, nil, invalidBundle(bundleManifest + " exceeds 1Mb")
+	}
+	rc, err := manifest.Open()
+	if err != nil {
+		return nil, nil, invalidBundle(bundleManifest + " can't be read")
+	}
+	defer rc.Close()
+
+	var bundle questModel.Bundle
+	if err := json.NewDecoder(io.LimitReader(rc, bundleManifestMaxSize)).Decode(&bundle); err != nil {
+		return nil, nil, invalidBundle(bundleManifest + " is not valid JSON")
+	}
+
+	if bundle.Version < 1 || bundle.Version > questModel.BundleVersion {
+		return nil, nil, invalidBundle(fmt.Sprintf("version %d is not supported", bundle.Version))
+	}
+	if bundle.Quest == nil || bundle.Quest.Name == nil || *bundle.Quest.Name == "" {
+		return nil, nil, invalidBundle("quest is missing")
+	}
+	if err := validateBundleSteps(bundle.Quest.Steps); err != nil {
+		return nil, nil, err
+	}
+
+	media := map[string]*zip.File{}
+	links := map[string]bool{}
+	unique := make([]questModel.BundleMedia, 0, len(bundle.Media))
+	var size uint64
+	for _, m := range bundle.Media {
+		if links[m.Link] {
+			continue
+		}
+		links[m.Link] = true
+		unique = append(unique, m)
+		if _, ok := media[m.File]; ok {
+			continue
+		}
+
+		f, ok := files[m.File]
+		if !ok {
+			return nil, nil, invalidBundle(fmt.Sprintf("%s is missing", m.File))
+		}
+		if f.UncompressedSize64 > mediaMaxSize {
+			return nil, nil, invalidBundle(fmt.Sprintf("%s exceeds 5Mb", m.File))
+		}
+		if size += f.UncompressedSize64; size > bundleMaxUncompressedSize {
+			return nil, nil, invalidBundle("media files exceed 200Mb")
+		}
+
+		ext := filepath.Ext(m.File)
+		switch mediaModel.MediaType(m.Type) {
+		case mediaModel.Image:
+			ok = helpers.SliceContains(imagesExt, ext)
+		case mediaModel.Sound:
+			ok = helpers.SliceContains(soundExt, ext)
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, nil, invalidBundle(fmt.Sprintf("%s is not a valid %s file", m.File, m.Type))
+		}
+
+		media[m.File] = f
+	}
+	bundle.Media = unique
+
+	return &bundle, media, nil
+}
+
+// validateBundleSteps checks the steps can be stored, their content is checked when the quest is sent.
+func validateBundleSteps(steps []questModel.Step) error {
+	sorts := map[int]bool{}
+	for i, step := range steps {
+		if step.Sort == nil {
+			return invalidBundle(fmt.Sprintf("step %d: sort is missing", i+1))
+		}
+		n := *step.Sort
+		if sorts[n] {
+			return invalidBundle(fmt.Sprintf("step %d: sort is duplicated", n))
+		}
+		sorts[n] = true
+
+		if step.Description == nil || strings.TrimSpace(*step.Description) == "" {
+			return invalidBundle(fmt.Sprintf("step %d: description is empty", n))
+		}
+		if step.QuestionType == nil || !step.QuestionType.IsValid() {
+			return invalidBundle(fmt.Sprintf("step %d: question_type is invalid", n))
+		}
+		if step.QuestionContent == nil {
+			return invalidBundle(fmt.Sprintf("step %d: question_content is missing", n))
+		}
+		if step.AnswerType == nil || *step.AnswerType != questModel.AnswerText {
+			return invalidBundle(fmt.Sprintf("step %d: answer_type is invalid", n))
+		}
+		if step.AnswerContent == nil {
+			return invalidBundle(fmt.Sprintf("step %d: answer_content is missing", n))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// catalogueDefaultLimit is the page size of the catalogue when no limit is given.
+	catalogueDefaultLimit = 20
+	// catalogueMaxLimit caps the page size of the catalogue.
+	catalogueMaxLimit = 50
+)
+
+// setVisibility makes the quest public, so it is listed in the catalogue and can be shared via its slug, or private.
+func (s *Server) setVisibility(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, questModel.SetVisibilityRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	quest, err := s.quests.SetVisibility(ctx, mux.Vars(r)["id"], req.Visibility)
+	if err != nil {
+		logging.From(ctx).Error("failed to set quest visibility", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+// getCatalogue lists the public quests, only the ones of a theme with ?theme=, the most played first with
+// ?sort=popular. The quests are paginated with offset and limit, the total count is returned in meta.
+func (s *Server) getCatalogue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+
+	filter := questModel.CatalogueFilter{
+		Theme:  questModel.Theme(query.Get("theme")),
+		Sort:   questModel.CatalogueRecent,
+		Offset: 0,
+		Limit:  catalogueDefaultLimit,
+	}
+	if filter.Theme != "" && !filter.Theme.IsValid() {
+		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid theme")))
+		return
+	}
+	if v := query.Get("sort"); v != "" {
+		if filter.Sort = questModel.CatalogueSort(v); !filter.Sort.IsValid() {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid sort")))
+			return
+		}
+	}
+
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid limit")))
+			return
+		}
+	}
+	if filter.Limit > catalogueMaxLimit {
+		filter.Limit = catalogueMaxLimit
+	}
+	if v := query.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil || filter.Offset < 0 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid offset")))
+			return
+		}
+	}
+
+	quests, meta, err := s.quests.GetCatalogue(ctx, filter)
+	if err != nil {
+		logging.From(ctx).Error("failed to get catalogue", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponseWithMeta(ctx, w, quests, meta)
+}
+
+// getCatalogueQuest shows the public quest a shared link points to, without spoilers.
+func (s *Server) getCatalogueQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quest, err := s.quests.GetCatalogueQuest(ctx, mux.Vars(r)["slug"])
+	if err != nil {
+		logging.From(ctx).Error("failed to get catalogue quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+// enrollQuest sends the public quest to the user, who can start playing it right away.
+func (s *Server) enrollQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, err := s.users.GetUser(ctx, ctx.Value(ContextUserIdKey).(string))
+	if err != nil {
+		logging.From(ctx).Error("failed to find user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	ql, err := s.quests.EnrollQuest(ctx, mux.Vars(r)["slug"], *user.Email, user.FullName())
+	if err != nil {
+		logging.From(ctx).Error("failed to enroll in quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, ql)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"github.com/superhorsy/quest-app-backend/internal/users/model"
+	"go.uber.org/zap"
+)
+
+// ErrCollaboratorRequired is returned when inviting a collaborator without their email or nickname.
+const ErrCollaboratorRequired = errors.Error("collaborator_required: email or nickname of the collaborator is required")
+
+// ShareQuestRequest invites a user to collaborate on a quest by their email or nickname.
+type ShareQuestRequest struct {
+	Email    string                      `json:"email"`
+	Nickname string                      `json:"nickname"`
+	Role     questModel.CollaboratorRole `json:"role"`
+}
+
+func (s *Server) getCollaborators(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	collaborators, err := s.quests.GetCollaborators(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to get collaborators", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, collaborators)
+}
+
+// shareQuest invites a user to collaborate on the quest, inviting the same user again changes their role. The response
+// is the same whether or not the email or nickname belongs to an active account, the invitee shows up among the
+// collaborators once they accept.
+func (s *Server) shareQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, ShareQuestRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	var u *model.User
+	switch {
+	case req.Email != "":
+		u, err = s.users.GetUserByEmail(ctx, req.Email)
+	case req.Nickname != "":
+		u, err = s.users.GetUserByNickname(ctx, req.Nickname)
+	default:
+		handleError(ctx, w, ErrCollaboratorRequired.Wrap(errors.ErrValidation))
+		return
+	}
+	if err != nil && !errors.Is(err, errors.ErrNotFound) {
+		logging.From(ctx).Error("failed to find collaborator", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	var userId *string
+	if u != nil && !u.IsDisabled() {
+		userId = u.ID
+	}
+
+	if err := s.quests.ShareQuest(ctx, mux.Vars(r)["id"], userId, req.Role); err != nil {
+		logging.From(ctx).Error("failed to share quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+// getInvitations lists the quests the user was invited to collaborate on and hasn't accepted yet.
+func (s *Server) getInvitations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quests, err := s.quests.GetInvitations(ctx, ctx.Value(ContextUserIdKey).(string))
+	if err != nil {
+		logging.From(ctx).Error("failed to fetch invitations", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quests)
+}
+
+// acceptInvitation gives the user access to the quest they were invited to.
+func (s *Server) acceptInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.quests.AcceptInvitation(ctx, mux.Vars(r)["id"]); err != nil {
+		logging.From(ctx).Error("failed to accept invitation", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+// unshareQuest removes a collaborator from the quest, collaborators may remove themselves to leave the quest.
+func (s *Server) unshareQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+
+	if err := s.quests.UnshareQuest(ctx, vars["id"], vars["userId"]); err != nil {
+		logging.From(ctx).Error("failed to unshare quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+// getSharedQuests lists the quests of other authors the user collaborates on.
+func (s *Server) getSharedQuests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+
+	var err error
+	limit, offset := 50, 0
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid limit")))
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid offset")))
+			return
+		}
+	}
+
+	quests, meta, err := s.quests.GetSharedQuests(ctx, ctx.Value(ContextUserIdKey).(string), offset, limit)
+	if err != nil {
+		logging.From(ctx).Error("failed to fetch shared quests", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponseWithMeta(ctx, w, quests, meta)
+}
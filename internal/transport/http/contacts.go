@@ -0,0 +1,137 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/contacts/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	// contactsDefaultLimit is the page size of the contacts list when no limit is given.
+	contactsDefaultLimit = 20
+	// contactsMaxLimit caps the page size of the contacts list.
+	contactsMaxLimit = 100
+)
+
+// getContacts lists the address book of the user, the q parameter autocompletes by name or email.
+func (s *Server) getContacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+
+	var limit, offset int64 = contactsDefaultLimit, 0
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.ParseInt(v, 10, 64); err != nil || limit < 1 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid limit")))
+			return
+		}
+	}
+	if limit > contactsMaxLimit {
+		limit = contactsMaxLimit
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.ParseInt(v, 10, 64); err != nil || offset < 0 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid offset")))
+			return
+		}
+	}
+
+	contacts, err := s.contacts.GetContacts(ctx, ctx.Value(ContextUserIdKey).(string), query.Get("q"), offset, limit)
+	if err != nil {
+		logging.From(ctx).Error("failed to get contacts", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, contacts)
+}
+
+func (s *Server) getContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	contact, err := s.contacts.GetContact(ctx, ctx.Value(ContextUserIdKey).(string), mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to get contact", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, contact)
+}
+
+func (s *Server) createContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	contact, err := parseContact(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	createdContact, err := s.contacts.CreateContact(ctx, ctx.Value(ContextUserIdKey).(string), contact)
+	if err != nil {
+		logging.From(ctx).Error("failed to create contact", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, createdContact)
+}
+
+func (s *Server) updateContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	contact, err := parseContact(r)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	contact.ID = &id
+
+	updatedContact, err := s.contacts.UpdateContact(ctx, ctx.Value(ContextUserIdKey).(string), contact)
+	if err != nil {
+		logging.From(ctx).Error("failed to update contact", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, updatedContact)
+}
+
+func (s *Server) deleteContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.contacts.DeleteContact(ctx, ctx.Value(ContextUserIdKey).(string), mux.Vars(r)["id"]); err != nil {
+		logging.From(ctx).Error("failed to delete contact", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+func parseContact(r *http.Request) (*model.Contact, error) {
+	contact, err := parseBodyIntoStruct(r, model.Contact{})
+	if err != nil {
+		return nil, err
+	}
+
+	if contact.Email != nil {
+		err = validation([]Validation{{Value: *contact.Email, Valid: "email", Error: errors.New("Invalid email")}})
+		if err != nil {
+			logging.From(r.Context()).Error("validation failed", zap.Error(err))
+			return nil, errors.ErrInvalidRequest.Wrap(err)
+		}
+	}
+
+	return contact, nil
+}
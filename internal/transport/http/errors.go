@@ -27,8 +27,12 @@ func handleError(ctx context.Context, w http.ResponseWriter, err error) {
 		w.WriteHeader(http.StatusBadRequest)
 	case errors.Is(err, errors.ErrNotFound):
 		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errors.ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
 	case errors.Is(err, errors.ErrForbidden):
 		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, errors.ErrTooManyRequests):
+		w.WriteHeader(http.StatusTooManyRequests)
 	case errors.Is(err, errors.ErrUnknown):
 		fallthrough
 	default:
@@ -5,14 +5,20 @@ package http
 import (
 	"context"
 	"encoding/json"
+	authModel "github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/auth/oidc"
+	contactModel "github.com/superhorsy/quest-app-backend/internal/contacts/model"
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
 	"github.com/superhorsy/quest-app-backend/internal/core/logging"
 	mediaModel "github.com/superhorsy/quest-app-backend/internal/media/model"
 	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
 	"go.uber.org/zap"
 	"io"
-	"mime/multipart"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/superhorsy/quest-app-backend/internal/users/model"
@@ -23,28 +29,126 @@ type Users interface {
 	CreateUser(ctx context.Context, user *model.UserWithPass) (*model.User, error)
 	GetUser(ctx context.Context, id string) (*model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByNickname(ctx context.Context, nickname string) (*model.User, error)
 	FindUsers(ctx context.Context, filters []model.Filter, offset, limit int64) ([]*model.User, error)
 	UpdateUser(ctx context.Context, user *model.UserWithPass) (*model.User, error)
 	DeleteUser(ctx context.Context, id string) error
+	VerifyEmail(ctx context.Context, id string, email string) (*model.User, error)
+	ListUsers(ctx context.Context, offset, limit int64) ([]*model.User, error)
+	SetRole(ctx context.Context, id string, role model.Role) (*model.User, error)
+	SetDisabled(ctx context.Context, id string, disabled bool) (*model.User, error)
+	SetAvatar(ctx context.Context, id string, avatarId *string) (*model.User, error)
+	ValidatePassword(password string) error
+	CheckPassword(ctx context.Context, user *model.User, password string) (bool, error)
 }
 
 // Quests represents a type that can provide CRUD operations on quests.
 type Quests interface {
 	CreateQuest(ctx context.Context, quest *questModel.QuestWithSteps) (*questModel.QuestWithSteps, error)
 	GetQuest(ctx context.Context, id string) (*questModel.QuestWithSteps, error)
+	InspectQuest(ctx context.Context, id string) (*questModel.QuestWithSteps, error)
 	UpdateQuest(ctx context.Context, quest *questModel.QuestWithSteps) (*questModel.QuestWithSteps, error)
-	GetQuestsByUser(ctx context.Context, uuid string, offset int, limit int) ([]questModel.Quest, *questModel.Meta, error)
+	GetQuestsByUser(ctx context.Context, uuid string, state questModel.State, offset int, limit int) ([]questModel.Quest, *questModel.Meta, error)
 	GetQuestsAvailable(ctx context.Context, email string, offset int, limit int, finished bool) ([]questModel.QuestAvailable, *questModel.Meta, error)
 	DeleteQuest(ctx context.Context, id string) error
 	CreateAssignment(ctx context.Context, request questModel.SendQuestRequest) error
 	GetAssignment(ctx context.Context, questId string) (*questModel.QuestLine, error)
 	StartQuest(ctx context.Context, questId string, userId *string) (*questModel.QuestLine, error)
 	CheckAnswer(ctx context.Context, questId string, userId *string, answer *questModel.Answer) (*questModel.QuestLine, error)
+	GetGuestAssignment(ctx context.Context, questId string, email string) (*questModel.QuestLine, error)
+	StartGuestQuest(ctx context.Context, questId string, email string) (*questModel.QuestLine, error)
+	CheckGuestAnswer(ctx context.Context, questId string, email string, answer *questModel.Answer) (*questModel.QuestLine, error)
+	ClaimAssignment(ctx context.Context, questId string, guestEmail string, email string) error
+	GetUserQuests(ctx context.Context, userId string) ([]questModel.QuestWithSteps, error)
+	GetPublishedQuests(ctx context.Context, ownerId string, offset int, limit int) ([]questModel.QuestSummary, *questModel.Meta, error)
+	GetAuthorStats(ctx context.Context, ownerId string) (*questModel.AuthorStats, error)
+	CreateStep(ctx context.Context, questId string, step *questModel.Step) (*questModel.Step, error)
+	UpdateStep(ctx context.Context, questId string, step *questModel.Step) (*questModel.Step, error)
+	DeleteStep(ctx context.Context, questId string, stepId string) error
+	ReorderSteps(ctx context.Context, questId string, stepIds []string) ([]questModel.Step, error)
+	GetVersions(ctx context.Context, questId string) ([]questModel.QuestVersion, error)
+	GetVersion(ctx context.Context, questId string, version int) (*questModel.QuestVersion, error)
+	MigrateRecipients(ctx context.Context, questId string, emails []string) (*questModel.MigrateRecipientsResponse, error)
+	PublishQuest(ctx context.Context, id string) (*questModel.QuestWithSteps, error)
+	ArchiveQuest(ctx context.Context, id string) (*questModel.QuestWithSteps, error)
+	GetTrash(ctx context.Context, userId string) (*questModel.Trash, error)
+	RestoreQuest(ctx context.Context, id string) (*questModel.QuestWithSteps, error)
+	RestoreStep(ctx context.Context, questId string, stepId string) (*questModel.Step, error)
+	CloneQuest(ctx context.Context, id string, name *string, copyMedia bool) (*questModel.QuestWithSteps, error)
+	GetTemplates(ctx context.Context, theme questModel.Theme) ([]questModel.Template, error)
+	GetTemplate(ctx context.Context, id string) (*questModel.Template, error)
+	CreateTemplate(ctx context.Context, t *questModel.Template) (*questModel.Template, error)
+	UpdateTemplate(ctx context.Context, t *questModel.Template) (*questModel.Template, error)
+	DeleteTemplate(ctx context.Context, id string) error
+	CreateQuestFromTemplate(ctx context.Context, templateId string, values map[string]string) (*questModel.QuestWithSteps, error)
+	ImportQuest(ctx context.Context, quest *questModel.QuestWithSteps, links map[string]string) (*questModel.QuestWithSteps, error)
+	GetCollaborators(ctx context.Context, questId string) ([]questModel.Collaborator, error)
+	ShareQuest(ctx context.Context, questId string, userId *string, role questModel.CollaboratorRole) error
+	GetInvitations(ctx context.Context, userId string) ([]questModel.SharedQuest, error)
+	AcceptInvitation(ctx context.Context, questId string) error
+	UnshareQuest(ctx context.Context, questId string, userId string) error
+	GetSharedQuests(ctx context.Context, userId string, offset int, limit int) ([]questModel.SharedQuest, *questModel.Meta, error)
+	SetVisibility(ctx context.Context, id string, visibility questModel.Visibility) (*questModel.QuestWithSteps, error)
+	GetCatalogue(ctx context.Context, filter questModel.CatalogueFilter) ([]questModel.CatalogueQuest, *questModel.Meta, error)
+	GetCatalogueQuest(ctx context.Context, slug string) (*questModel.CatalogueQuest, error)
+	EnrollQuest(ctx context.Context, slug string, email string, name string) (*questModel.QuestLine, error)
+	GetUserAssignments(ctx context.Context, email string) ([]questModel.Assignment, error)
 }
 
 type Media interface {
-	UploadFile(ctx context.Context, file multipart.File, filename string, mediaType mediaModel.MediaType) (*mediaModel.MediaRecord, error)
+	UploadFile(ctx context.Context, file io.Reader, filename string, mediaType mediaModel.MediaType) (*mediaModel.MediaRecord, error)
 	GetMedia(ctx context.Context, id string) (*mediaModel.MediaRecord, error)
+	GetMediaByLink(ctx context.Context, link string) (*mediaModel.MediaRecord, error)
+	DeleteMedia(ctx context.Context, record *mediaModel.MediaRecord) error
+	GetUserMedia(ctx context.Context, userId string) ([]mediaModel.MediaRecord, error)
+	GetUnsharedUserMedia(ctx context.Context, userId string) ([]mediaModel.MediaRecord, error)
+	OpenFile(ctx context.Context, record *mediaModel.MediaRecord) (io.ReadCloser, error)
+	DeleteFiles(ctx context.Context, records []mediaModel.MediaRecord)
+}
+
+// Auth represents a type that can issue and verify user tokens.
+type Auth interface {
+	IssueTokens(ctx context.Context, userId string, client authModel.Client) (*authModel.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string, client authModel.Client) (*authModel.Tokens, error)
+	Logout(ctx context.Context, refreshToken string) error
+	Authenticate(ctx context.Context, authHeader string) (*authModel.Claims, error)
+	RevokeUserSessions(ctx context.Context, userId string) error
+	RevokePersonalAccessTokens(ctx context.Context, userId string) error
+	GetSessions(ctx context.Context, userId string) ([]authModel.Session, error)
+	RevokeSession(ctx context.Context, userId string, sessionId string) error
+	CreatePersonalAccessToken(ctx context.Context, userId string, name string, scopes []string, expiresAt *time.Time) (*authModel.PersonalAccessToken, string, error)
+	GetPersonalAccessTokens(ctx context.Context, userId string) ([]authModel.PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, userId string, id string) error
+	CreatePasswordResetToken(ctx context.Context, userId string) (string, error)
+	UsePasswordResetToken(ctx context.Context, token string) (string, error)
+	CreateEmailVerificationToken(ctx context.Context, userId string, email string) (string, error)
+	UseEmailVerificationToken(ctx context.Context, token string) (string, string, error)
+	OIDCProviders() []string
+	StartOIDCLogin(ctx context.Context, provider string) (string, string, error)
+	FinishOIDCLogin(ctx context.Context, provider string, code string, state string) (*oidc.Claims, error)
+	GetIdentity(ctx context.Context, provider string, subject string) (*authModel.Identity, error)
+	LinkIdentity(ctx context.Context, userId string, provider string, claims *oidc.Claims) (*authModel.Identity, error)
+	CheckLoginAllowed(ctx context.Context, email string, ip string) error
+	RecordLoginAttempt(ctx context.Context, email string, userId *string, ip string, success bool) error
+	GetLoginAttempts(ctx context.Context, filter authModel.LoginAttemptFilter) ([]authModel.LoginAttempt, error)
+	EnrollTOTP(ctx context.Context, userId string, account string) (*authModel.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userId string, code string) ([]string, error)
+	GetTwoFactorStatus(ctx context.Context, userId string) (*authModel.TwoFactorStatus, error)
+	IsTwoFactorEnabled(ctx context.Context, userId string) (bool, error)
+	DisableTOTP(ctx context.Context, userId string, code string, recoveryCode string, ip string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId string, code string, ip string) ([]string, error)
+	CreateMFAChallenge(ctx context.Context, userId string) (string, error)
+	VerifyMFAChallenge(ctx context.Context, challengeToken string, code string, recoveryCode string, ip string) (string, error)
+}
+
+// Contacts represents a type that can manage the address books of users.
+type Contacts interface {
+	CreateContact(ctx context.Context, userId string, contact *contactModel.Contact) (*contactModel.Contact, error)
+	GetContact(ctx context.Context, userId string, id string) (*contactModel.Contact, error)
+	GetContacts(ctx context.Context, userId string, search string, offset int64, limit int64) ([]contactModel.Contact, error)
+	UpdateContact(ctx context.Context, userId string, contact *contactModel.Contact) (*contactModel.Contact, error)
+	DeleteContact(ctx context.Context, userId string, id string) error
+	SaveRecipient(ctx context.Context, userId string, email string, name string) error
 }
 
 // DB represents a type that can be used to interact with the database.
@@ -54,19 +158,23 @@ type DB interface {
 
 // Server represents an HTTP server that can handle requests for this microservice.
 type Server struct {
-	users  Users
-	quests Quests
-	db     DB
-	media  Media
+	users    Users
+	quests   Quests
+	db       DB
+	media    Media
+	auth     Auth
+	contacts Contacts
 }
 
 // New will instantiate a new instance of Server.
-func New(u Users, q Quests, db DB, m Media) *Server {
+func New(u Users, q Quests, db DB, m Media, a Auth, c Contacts) *Server {
 	return &Server{
-		users:  u,
-		quests: q,
-		media:  m,
-		db:     db,
+		users:    u,
+		quests:   q,
+		media:    m,
+		db:       db,
+		auth:     a,
+		contacts: c,
 	}
 }
 
@@ -75,6 +183,8 @@ func (s *Server) AddRoutes(r *mux.Router) error {
 
 	// Health check
 	r.HandleFunc("/health", s.healthCheck).Methods(http.MethodGet)
+	// Keys other services verify our tokens with
+	r.HandleFunc("/.well-known/jwks.json", s.getJWKS).Methods(http.MethodGet)
 
 	r = r.PathPrefix("/api").Subrouter()
 	r = r.PathPrefix("/v1").Subrouter()
@@ -85,33 +195,124 @@ func (s *Server) AddRoutes(r *mux.Router) error {
 	auth.Use(EnforceJSONHandler)
 	auth.Path("/login").Handler(http.HandlerFunc(s.login)).Methods(http.MethodPost)
 	auth.Path("/register").Handler(http.HandlerFunc(s.register)).Methods(http.MethodPost)
+	auth.Path("/auth/refresh").Handler(http.HandlerFunc(s.refresh)).Methods(http.MethodPost)
+	auth.Path("/auth/logout").Handler(http.HandlerFunc(s.logout)).Methods(http.MethodPost)
+	auth.Path("/auth/password/forgot").Handler(http.HandlerFunc(s.forgotPassword)).Methods(http.MethodPost)
+	auth.Path("/auth/password/reset").Handler(http.HandlerFunc(s.resetPassword)).Methods(http.MethodPost)
+	auth.Path("/auth/email/verify").Handler(http.HandlerFunc(s.verifyEmail)).Methods(http.MethodPost)
+	auth.Path("/auth/2fa/verify").Handler(http.HandlerFunc(s.verifyTwoFactor)).Methods(http.MethodPost)
+	auth.Path("/auth/oidc/providers").Handler(http.HandlerFunc(s.getOIDCProviders)).Methods(http.MethodGet)
+	auth.Path("/auth/oidc/{provider}/login").Handler(http.HandlerFunc(s.oidcLogin)).Methods(http.MethodGet)
+	auth.Path("/auth/oidc/{provider}/callback").Handler(http.HandlerFunc(s.oidcCallback)).Methods(http.MethodPost)
 
 	// Media handler
 	media := r.Name("media").Subrouter()
-	media.Use(authHandler)
+	media.Use(s.authHandler)
 	media.Use(JsonResponse)
-	media.HandleFunc("/media/upload", s.uploadMedia).Methods(http.MethodPost)
+	media.Handle("/media/upload", s.allow(PermissionAuthorQuests, s.uploadMedia)).Methods(http.MethodPost)
 	media.HandleFunc("/media/{id}", s.getMedia).Methods(http.MethodGet)
+	// Quest bundles are uploaded as multipart forms, so they are imported outside the JSON-only api routes
+	media.Handle("/quests/import", s.allow(PermissionAuthorQuests, s.importQuest)).Methods(http.MethodPost)
+
+	// Quests played via magic links, without an account
+	guest := r.Name("guest").Subrouter()
+	guest.Use(s.guestHandler)
+	guest.Use(JsonResponse)
+	guest.Use(EnforceJSONHandler)
+	guest.HandleFunc("/guest/quests/{id}/start", s.startGuestQuest).Methods(http.MethodPost)
+	guest.HandleFunc("/guest/quests/{id}/next", s.checkGuestAnswer).Methods(http.MethodPost)
+	guest.HandleFunc("/guest/quests/{id}/status", s.guestStatus).Methods(http.MethodGet)
 
 	api := r.Name("api").Subrouter()
-	api.Use(authHandler)
+	api.Use(s.authHandler)
 	api.Use(JsonResponse)
 	api.Use(EnforceJSONHandler)
 
 	// Profile
 	api.HandleFunc("/profile", s.getCurrentUser).Methods(http.MethodGet)
 	api.HandleFunc("/profile", s.updateUser).Methods(http.MethodPut)
+	api.HandleFunc("/profile", s.deleteProfile).Methods(http.MethodDelete)
+	api.HandleFunc("/profile/export", s.exportProfile).Methods(http.MethodGet)
+	api.HandleFunc("/profile/sessions", s.getSessions).Methods(http.MethodGet)
+	api.HandleFunc("/profile/sessions/{id}", s.revokeSession).Methods(http.MethodDelete)
+	api.HandleFunc("/profile/tokens", s.getTokens).Methods(http.MethodGet)
+	api.HandleFunc("/profile/tokens", s.createToken).Methods(http.MethodPost)
+	api.HandleFunc("/profile/tokens/{id}", s.revokeToken).Methods(http.MethodDelete)
+	api.HandleFunc("/profile/avatar", s.setAvatar).Methods(http.MethodPut)
+	api.HandleFunc("/profile/avatar", s.deleteAvatar).Methods(http.MethodDelete)
+	api.HandleFunc("/profile/email/verify", s.resendEmailVerification).Methods(http.MethodPost)
+	api.HandleFunc("/profile/2fa", s.getTwoFactorStatus).Methods(http.MethodGet)
+	api.HandleFunc("/profile/2fa", s.disableTwoFactor).Methods(http.MethodDelete)
+	api.HandleFunc("/profile/2fa/enroll", s.enrollTwoFactor).Methods(http.MethodPost)
+	api.HandleFunc("/profile/2fa/confirm", s.confirmTwoFactor).Methods(http.MethodPost)
+	api.HandleFunc("/profile/2fa/recovery-codes", s.regenerateRecoveryCodes).Methods(http.MethodPost)
+	// Users
+	api.Handle("/users/search", s.allow(PermissionAuthorQuests, s.searchPublicUsers)).Methods(http.MethodGet)
+	// Contacts
+	api.Handle("/contacts", s.allow(PermissionAuthorQuests, s.getContacts)).Methods(http.MethodGet)
+	api.Handle("/contacts", s.allow(PermissionAuthorQuests, s.createContact)).Methods(http.MethodPost)
+	api.Handle("/contacts/{id}", s.allow(PermissionAuthorQuests, s.getContact)).Methods(http.MethodGet)
+	api.Handle("/contacts/{id}", s.allow(PermissionAuthorQuests, s.updateContact)).Methods(http.MethodPut)
+	api.Handle("/contacts/{id}", s.allow(PermissionAuthorQuests, s.deleteContact)).Methods(http.MethodDelete)
 	// Quests
-	api.HandleFunc("/quests", s.createQuest).Methods(http.MethodPost)
-	api.HandleFunc("/quests/created", s.getQuestsByUser).Methods(http.MethodGet)
-	api.HandleFunc("/quests/available", s.getAvailableQuests).Methods(http.MethodGet)
-	api.HandleFunc("/quests/{id}", s.getQuest).Methods(http.MethodGet)
-	api.HandleFunc("/quests/{id}", s.updateQuest).Methods(http.MethodPut)
-	api.HandleFunc("/quests/{id}", s.deleteQuest).Methods(http.MethodDelete)
-	api.HandleFunc("/quests/{id}/send", s.sendQuest).Methods(http.MethodPost)
-	api.HandleFunc("/quests/{id}/start", s.startQuest).Methods(http.MethodPost)
-	api.HandleFunc("/quests/{id}/next", s.checkAnswer).Methods(http.MethodPost)
-	api.HandleFunc("/quests/{id}/status", s.status).Methods(http.MethodGet)
+	api.Handle("/quests", s.allow(PermissionAuthorQuests, s.createQuest)).Methods(http.MethodPost)
+	api.Handle("/quests/created", s.allow(PermissionAuthorQuests, s.getQuestsByUser)).Methods(http.MethodGet)
+	api.Handle("/quests/available", s.verifiedEmailHandler(s.allow(PermissionPlayQuests, s.getAvailableQuests))).Methods(http.MethodGet)
+	api.Handle("/quests/claim", s.verifiedEmailHandler(s.allow(PermissionPlayQuests, s.claimQuest))).Methods(http.MethodPost)
+	api.Handle("/quests/shared", s.allow(PermissionAuthorQuests, s.getSharedQuests)).Methods(http.MethodGet)
+	api.Handle("/quests/invitations", s.allow(PermissionAuthorQuests, s.getInvitations)).Methods(http.MethodGet)
+	api.Handle("/quests/trash", s.allow(PermissionAuthorQuests, s.getTrash)).Methods(http.MethodGet)
+	api.Handle("/quests/from-template", s.allow(PermissionAuthorQuests, s.createQuestFromTemplate)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}", s.allow(PermissionAuthorQuests, s.getQuest)).Methods(http.MethodGet)
+	api.Handle("/quests/{id}", s.allow(PermissionAuthorQuests, s.updateQuest)).Methods(http.MethodPut)
+	api.Handle("/quests/{id}", s.allow(PermissionAuthorQuests, s.deleteQuest)).Methods(http.MethodDelete)
+	api.Handle("/quests/{id}/send", s.allow(PermissionAuthorQuests, s.sendQuest)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/publish", s.allow(PermissionAuthorQuests, s.publishQuest)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/archive", s.allow(PermissionAuthorQuests, s.archiveQuest)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/visibility", s.allow(PermissionAuthorQuests, s.setVisibility)).Methods(http.MethodPut)
+	api.Handle("/quests/{id}/restore", s.allow(PermissionAuthorQuests, s.restoreQuest)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/clone", s.allow(PermissionAuthorQuests, s.cloneQuest)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/export", s.allow(PermissionAuthorQuests, s.exportQuest)).Methods(http.MethodGet)
+	api.Handle("/quests/{id}/collaborators", s.allow(PermissionAuthorQuests, s.getCollaborators)).Methods(http.MethodGet)
+	api.Handle("/quests/{id}/collaborators", s.allow(PermissionAuthorQuests, s.shareQuest)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/collaborators/{userId}", s.allow(PermissionAuthorQuests, s.unshareQuest)).Methods(http.MethodDelete)
+	api.Handle("/quests/{id}/invitation/accept", s.allow(PermissionAuthorQuests, s.acceptInvitation)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/steps", s.allow(PermissionAuthorQuests, s.createStep)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/steps/order", s.allow(PermissionAuthorQuests, s.reorderSteps)).Methods(http.MethodPut)
+	api.Handle("/quests/{id}/steps/{stepId}", s.allow(PermissionAuthorQuests, s.updateStep)).Methods(http.MethodPut)
+	api.Handle("/quests/{id}/steps/{stepId}", s.allow(PermissionAuthorQuests, s.deleteStep)).Methods(http.MethodDelete)
+	api.Handle("/quests/{id}/steps/{stepId}/restore", s.allow(PermissionAuthorQuests, s.restoreStep)).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/versions", s.allow(PermissionAuthorQuests, s.getVersions)).Methods(http.MethodGet)
+	api.Handle("/quests/{id}/versions/{version:[0-9]+}", s.allow(PermissionAuthorQuests, s.getVersion)).Methods(http.MethodGet)
+	api.Handle("/quests/{id}/recipients/migrate", s.allow(PermissionAuthorQuests, s.migrateRecipients)).Methods(http.MethodPost)
+	api.Handle("/templates", s.allow(PermissionAuthorQuests, s.getTemplates)).Methods(http.MethodGet)
+	api.Handle("/templates/{id}", s.allow(PermissionAuthorQuests, s.getTemplate)).Methods(http.MethodGet)
+	// Quests assigned to the user by email can be played only after the email is verified
+	api.Handle("/quests/{id}/start", s.verifiedEmailHandler(s.allow(PermissionPlayQuests, s.startQuest))).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/next", s.verifiedEmailHandler(s.allow(PermissionPlayQuests, s.checkAnswer))).Methods(http.MethodPost)
+	api.Handle("/quests/{id}/status", s.verifiedEmailHandler(s.allow(PermissionPlayQuests, s.status))).Methods(http.MethodGet)
+	api.Handle("/catalogue/{slug}/enroll", s.verifiedEmailHandler(s.allow(PermissionPlayQuests, s.enrollQuest))).Methods(http.MethodPost)
+
+	// Admin
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Handle("/users", s.allow(PermissionManageUsers, s.listUsers)).Methods(http.MethodGet)
+	admin.Handle("/users/search", s.allow(PermissionManageUsers, s.searchUsers)).Methods(http.MethodPost)
+	admin.Handle("/users/{id}", s.allow(PermissionManageUsers, s.getUser)).Methods(http.MethodGet)
+	admin.Handle("/users/{id}/role", s.allow(PermissionManageUsers, s.setUserRole)).Methods(http.MethodPut)
+	admin.Handle("/users/{id}/disable", s.allow(PermissionManageUsers, s.disableUser)).Methods(http.MethodPost)
+	admin.Handle("/users/{id}/enable", s.allow(PermissionManageUsers, s.enableUser)).Methods(http.MethodPost)
+	admin.Handle("/login-attempts", s.allow(PermissionManageUsers, s.getLoginAttempts)).Methods(http.MethodGet)
+	admin.Handle("/quests/{id}", s.allow(PermissionInspectQuests, s.inspectQuest)).Methods(http.MethodGet)
+	admin.Handle("/templates", s.allow(PermissionManageTemplates, s.createTemplate)).Methods(http.MethodPost)
+	admin.Handle("/templates/{id}", s.allow(PermissionManageTemplates, s.updateTemplate)).Methods(http.MethodPut)
+	admin.Handle("/templates/{id}", s.allow(PermissionManageTemplates, s.deleteTemplate)).Methods(http.MethodDelete)
+
+	// Public profiles, registered after the api routes so /users/search isn't taken for a nickname
+	public := r.Name("public").Subrouter()
+	public.Use(JsonResponse)
+	public.HandleFunc("/users/{nickname}", s.getPublicProfile).Methods(http.MethodGet)
+	public.HandleFunc("/catalogue", s.getCatalogue).Methods(http.MethodGet)
+	public.HandleFunc("/catalogue/{slug}", s.getCatalogueQuest).Methods(http.MethodGet)
 
 	return nil
 }
@@ -126,9 +327,11 @@ func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 type Response struct {
-	Data interface{} `json:"data"`
-	Meta interface{} `json:"meta,omitempty"`
-	Jwt  string      `json:"jwt,omitempty"`
+	Data         interface{} `json:"data"`
+	Meta         interface{} `json:"meta,omitempty"`
+	Jwt          string      `json:"jwt,omitempty"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+	ExpiresIn    int64       `json:"expires_in,omitempty"`
 }
 
 func handleResponseWithMeta(ctx context.Context, w http.ResponseWriter, data interface{}, meta interface{}) {
@@ -176,6 +379,83 @@ func handleResponse(ctx context.Context, w http.ResponseWriter, data interface{}
 	w.WriteHeader(http.StatusOK)
 }
 
+func handleTokenResponse(ctx context.Context, w http.ResponseWriter, data interface{}, tokens *authModel.Tokens) {
+
+	jsonRes := Response{
+		Data:         data,
+		Jwt:          tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+
+	dataBytes, err := json.Marshal(jsonRes)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	if _, err := w.Write(dataBytes); err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// clientIP returns the IP address of the client. X-Forwarded-For is only read when the request comes from
+// a trusted proxy, and it is walked from the right so a client can't spoof its address by prepending entries.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+
+	proxies := helpers.GetConfig(r.Context()).TrustedProxies
+	if !isTrustedProxy(proxies, ip) {
+		return ip.String()
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(proxies, ip) {
+			break
+		}
+	}
+
+	return ip.String()
+}
+
+// isTrustedProxy reports whether the IP matches one of the proxies, given as IPs or CIDRs.
+func isTrustedProxy(proxies []string, ip net.IP) bool {
+	for _, proxy := range proxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientInfo returns the device the request is made from.
+func clientInfo(r *http.Request) authModel.Client {
+	return authModel.Client{
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}
+}
+
 func parseBodyIntoStruct[K any](r *http.Request, target K) (*K, error) {
 	ctx := r.Context()
 	data, err := io.ReadAll(r.Body)
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"context"
+	"os"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"go.uber.org/zap"
+)
+
+const mailImage = "https://questy.fun/files/10d26a38-2fdf-4f48-adff-3e052e7466f5.png"
+
+// mailTemplateData is passed to every email template from the config directory.
+type mailTemplateData struct {
+	Name string
+	URL  string
+	IMG  string
+}
+
+// sendEmail sends the email in background if mailing is enabled. Failures are only logged.
+func sendEmail(ctx context.Context, email string, subject string, template string, data mailTemplateData) {
+	if os.Getenv("MAILING_ENABLED") != "true" {
+		return
+	}
+	if data.IMG == "" {
+		data.IMG = mailImage
+	}
+
+	go func() {
+		err := helpers.SendEmail(email, subject, template, data)
+		if err != nil {
+			logging.From(ctx).Error("failed to send email", zap.Error(err))
+			return
+		}
+	}()
+}
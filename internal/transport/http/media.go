@@ -12,6 +12,22 @@ import (
 	"path/filepath"
 )
 
+// mediaMaxSize is the largest media file which can be uploaded.
+const mediaMaxSize = 5 << 20
+
+var (
+	imagesExt = []string{
+		".png",
+		".jpg",
+		".jpeg",
+	}
+	soundExt = []string{
+		".mp3",
+		".wav,",
+		".webm",
+	}
+)
+
 type MediaRequest struct {
 	Type model.MediaType `json:"type"`
 }
@@ -25,7 +41,7 @@ func (s *Server) uploadMedia(w http.ResponseWriter, r *http.Request) {
 	// upload of 5 MB files.
 	// left shift 5 << 20 which results in 5*2^20
 	// x << y, results in x*2^y
-	err := r.ParseMultipartForm(5 << 20)
+	err := r.ParseMultipartForm(mediaMaxSize)
 	if err != nil {
 		logging.From(ctx).Error("failed to upload file: file exceeds 5Mb", zap.Error(err))
 		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(err))
@@ -55,17 +71,6 @@ func (s *Server) uploadMedia(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	imagesExt := []string{
-		".png",
-		".jpg",
-		".jpeg",
-	}
-	soundExt := []string{
-		".mp3",
-		".wav,",
-		".webm",
-	}
-
 	fmt.Printf("Uploaded File: %+v", header.Filename)
 	fmt.Printf("File Size: %+v\n", header.Size)
 	fmt.Printf("MIME Header: %+v\n", header.Header)
@@ -2,8 +2,11 @@ package http
 
 import (
 	"context"
+	"github.com/gorilla/mux"
 	"github.com/superhorsy/quest-app-backend/internal/core/config"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
 	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+	"github.com/superhorsy/quest-app-backend/internal/users/model"
 	"mime"
 	"net/http"
 )
@@ -36,18 +39,76 @@ func JsonResponse(next http.Handler) http.Handler {
 	})
 }
 
-const ContextUserIdKey config.ContextKey = "userId"
+const (
+	ContextUserIdKey     config.ContextKey = "userId"
+	ContextUserRoleKey   config.ContextKey = "userRole"
+	ContextSessionIdKey  config.ContextKey = "sessionId"
+	ContextGuestEmailKey config.ContextKey = "guestEmail"
+)
 
-func authHandler(next http.Handler) http.Handler {
+func (s *Server) authHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
-		token, err := helpers.ParseToken(authHeader)
+		claims, err := s.auth.Authenticate(r.Context(), authHeader)
 		if err != nil {
+			if !errors.Is(err, errors.ErrUnauthorized) {
+				handleError(r.Context(), w, err)
+				return
+			}
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte(err.Error()))
 			return
 		}
-		ctx := context.WithValue(r.Context(), ContextUserIdKey, token["sub"])
+		ctx := context.WithValue(r.Context(), ContextUserIdKey, claims.UserID)
+		ctx = context.WithValue(ctx, ContextUserRoleKey, model.Role(claims.Role))
+		ctx = context.WithValue(ctx, ContextSessionIdKey, claims.SessionID)
+
+		if claims.Scopes != nil {
+			if err := checkTokenScopes(r, claims.Scopes); err != nil {
+				handleError(ctx, w, err)
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// ErrInvalidGuestToken is returned when the magic link of a quest is malformed, expired or issued for another quest.
+const ErrInvalidGuestToken = errors.Error("invalid_guest_token: quest link is invalid")
+
+// guestHandler lets guests play the quest their magic link was issued for, the token is sent as a bearer token.
+func (s *Server) guestHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		questId, email, err := helpers.ParseGuestToken(r.Header.Get("Authorization"))
+		if err != nil || questId != mux.Vars(r)["id"] {
+			handleError(ctx, w, ErrInvalidGuestToken.Wrap(errors.ErrUnauthorized))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, ContextGuestEmailKey, email)))
+	})
+}
+
+// ErrEmailNotVerified is returned when the user tries to access quests sent to an email they haven't confirmed yet.
+const ErrEmailNotVerified = errors.Error("email_not_verified: confirm your email address to access quests sent to it")
+
+func (s *Server) verifiedEmailHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		user, err := s.users.GetUser(ctx, ctx.Value(ContextUserIdKey).(string))
+		if err != nil {
+			handleError(ctx, w, err)
+			return
+		}
+		if !user.IsVerified() {
+			handleError(ctx, w, ErrEmailNotVerified.Wrap(errors.ErrForbidden))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/auth/oidc"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"github.com/superhorsy/quest-app-backend/internal/users/model"
+	userStore "github.com/superhorsy/quest-app-backend/internal/users/store"
+	"go.uber.org/zap"
+)
+
+const (
+	// ErrIdentityWithoutEmail is returned when the identity provider doesn't share the email of the user.
+	ErrIdentityWithoutEmail = errors.Error("identity_without_email: identity provider didn't share the email")
+	// ErrIdentityEmailNotVerified is returned when the email is already registered but the identity provider hasn't verified it.
+	ErrIdentityEmailNotVerified = errors.Error("identity_email_not_verified: email is already registered, sign in with password")
+	// ErrAccountEmailNotVerified is returned when the email is registered to an account which hasn't verified it yet.
+	ErrAccountEmailNotVerified = errors.Error("account_email_not_verified: email is already registered, sign in with password and verify it first")
+	// ErrLoginStateMismatch is returned when the callback doesn't come from the browser the login was started in.
+	ErrLoginStateMismatch = errors.Error("login_state_mismatch: sign in was started in another browser")
+)
+
+const (
+	// oidcStateCookie binds the state of an OIDC login to the browser it was started in.
+	oidcStateCookie = "oidc_state"
+	// oidcStateCookieTTL matches how long the state of an OIDC login is kept.
+	oidcStateCookieTTL = 10 * time.Minute
+)
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+func (s *Server) getOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	handleResponse(ctx, w, struct {
+		Providers []string `json:"providers"`
+	}{Providers: s.auth.OIDCProviders()})
+}
+
+func (s *Server) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	provider := mux.Vars(r)["provider"]
+
+	authURL, state, err := s.auth.StartOIDCLogin(ctx, provider)
+	if err != nil {
+		logging.From(ctx).Error("failed to start oidc login", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	// Otherwise an attacker could sign the victim into the attacker's account with a callback of their own login
+	setOIDCStateCookie(w, r, state, int(oidcStateCookieTTL.Seconds()))
+
+	handleResponse(ctx, w, struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}{AuthorizationURL: authURL})
+}
+
+func (s *Server) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	provider := mux.Vars(r)["provider"]
+
+	req, err := parseBodyIntoStruct(r, OIDCCallbackRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(req.State)) != 1 {
+		handleError(ctx, w, ErrLoginStateMismatch.Wrap(errors.ErrValidation))
+		return
+	}
+	setOIDCStateCookie(w, r, "", -1)
+
+	claims, err := s.auth.FinishOIDCLogin(ctx, provider, req.Code, req.State)
+	if err != nil {
+		logging.From(ctx).Error("failed to finish oidc login", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	var user *model.User
+	identity, err := s.auth.GetIdentity(ctx, provider, claims.Subject)
+	switch {
+	case err == nil:
+		user, err = s.users.GetUser(ctx, *identity.UserID)
+	case errors.Is(err, errors.ErrNotFound):
+		user, err = s.linkOIDCUser(ctx, provider, claims)
+	}
+	if err != nil {
+		logging.From(ctx).Error("failed to get user for identity", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	s.completeLogin(w, r, user)
+}
+
+// linkOIDCUser will link a new identity to the user registered with its email, or register a new user for it.
+func (s *Server) linkOIDCUser(ctx context.Context, provider string, claims *oidc.Claims) (*model.User, error) {
+	if claims.Email == "" {
+		return nil, ErrIdentityWithoutEmail.Wrap(errors.ErrValidation)
+	}
+
+	user, err := s.users.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		// Otherwise anyone could take over an account by registering its email at the provider
+		if !claims.EmailVerified {
+			return nil, ErrIdentityEmailNotVerified.Wrap(errors.ErrValidation)
+		}
+		// Otherwise whoever registered the email with a password first would keep access to the linked account
+		if !user.IsVerified() {
+			return nil, ErrAccountEmailNotVerified.Wrap(errors.ErrValidation)
+		}
+	case errors.Is(err, errors.ErrNotFound):
+		user, err = s.registerOIDCUser(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if _, err := s.auth.LinkIdentity(ctx, *user.ID, provider, claims); err != nil {
+		return nil, err
+	}
+
+	if claims.EmailVerified && !user.IsVerified() {
+		return s.users.VerifyEmail(ctx, *user.ID, *user.Email)
+	}
+
+	return user, nil
+}
+
+// setOIDCStateCookie stores the state of the OIDC login in the browser, a negative maxAge removes it.
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(helpers.GetConfig(r.Context()).AppURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) registerOIDCUser(ctx context.Context, claims *oidc.Claims) (*model.User, error) {
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+
+	nickname := claims.Nickname
+	if nickname == "" {
+		nickname, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	// The user signs in through the provider, a password can be set later with the password reset
+	password, err := helpers.GenerateToken()
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	newUser := func(nickname string) *model.UserWithPass {
+		return &model.UserWithPass{
+			User: &model.User{
+				FirstName: &firstName,
+				LastName:  &lastName,
+				Nickname:  &nickname,
+				Email:     &claims.Email,
+			},
+			Password: &password,
+		}
+	}
+
+	user, err := s.users.CreateUser(ctx, newUser(nickname))
+	if !errors.Is(err, userStore.ErrNicknameAlreadyUsed) {
+		return user, err
+	}
+
+	// Nicknames are unique, the user can pick a nicer one in the profile
+	suffix, err := helpers.GenerateToken()
+	if err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return s.users.CreateUser(ctx, newUser(nickname+"-"+strings.ToLower(suffix[:6])))
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	authModel "github.com/superhorsy/quest-app-backend/internal/auth/model"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/users/model"
+)
+
+const (
+	// ErrPermissionDenied is returned when the role of the user doesn't allow the action.
+	ErrPermissionDenied = errors.Error("permission_denied: not allowed for your role")
+	// ErrInsufficientScope is returned when the personal access token isn't allowed to make the request.
+	ErrInsufficientScope = errors.Error("insufficient_scope: not allowed for this token")
+)
+
+// Permission represents an action which is allowed only to some roles.
+type Permission string
+
+const (
+	// PermissionPlayQuests allows playing quests sent to the user.
+	PermissionPlayQuests Permission = "quests:play"
+	// PermissionAuthorQuests allows creating quests, uploading media for them and sending them.
+	PermissionAuthorQuests Permission = "quests:author"
+	// PermissionInspectQuests allows viewing quests of any user.
+	PermissionInspectQuests Permission = "quests:inspect"
+	// PermissionManageUsers allows listing users, changing their roles and disabling their accounts.
+	PermissionManageUsers Permission = "users:manage"
+	// PermissionManageTemplates allows curating the template library.
+	PermissionManageTemplates Permission = "templates:manage"
+)
+
+// rolePermissions is the policy deciding what each role is allowed to do.
+var rolePermissions = map[model.Role][]Permission{
+	model.RolePlayer: {PermissionPlayQuests},
+	model.RoleAuthor: {PermissionPlayQuests, PermissionAuthorQuests},
+	model.RoleAdmin: {PermissionPlayQuests, PermissionAuthorQuests, PermissionInspectQuests, PermissionManageUsers,
+		PermissionManageTemplates},
+}
+
+// HasPermission reports whether the role allows the action.
+func HasPermission(role model.Role, p Permission) bool {
+	for _, rp := range rolePermissions[role] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionHandler allows the request only to users whose role has the permission, it requires the authHandler.
+func (s *Server) permissionHandler(p Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			role, _ := ctx.Value(ContextUserRoleKey).(model.Role)
+			if !HasPermission(role, p) {
+				handleError(ctx, w, ErrPermissionDenied.Wrap(errors.ErrForbidden))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow serves the handler only to users whose role has the permission.
+func (s *Server) allow(p Permission, h http.HandlerFunc) http.Handler {
+	return s.permissionHandler(p)(h)
+}
+
+// tokenWriteScopes is the scope a personal access token needs for changing requests under the path prefix.
+// Changing requests anywhere else can be made only with a session, e.g. managing tokens or the profile.
+var tokenWriteScopes = []struct {
+	prefix string
+	scope  string
+}{
+	{"/api/v1/quests", authModel.ScopeQuestsWrite},
+	{"/api/v1/media", authModel.ScopeMediaWrite},
+}
+
+// checkTokenScopes returns an error if the scopes of a personal access token don't allow the request.
+func checkTokenScopes(r *http.Request, scopes []string) error {
+	required := ""
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		required = authModel.ScopeRead
+	default:
+		path := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				path = tpl
+			}
+		}
+		for _, s := range tokenWriteScopes {
+			if strings.HasPrefix(path, s.prefix) {
+				required = s.scope
+				break
+			}
+		}
+	}
+
+	if required != "" {
+		for _, scope := range scopes {
+			if scope == required {
+				return nil
+			}
+		}
+	}
+
+	return ErrInsufficientScope.Wrap(errors.ErrForbidden)
+}
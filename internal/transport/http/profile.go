@@ -0,0 +1,358 @@
+package http
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+	authModel "github.com/superhorsy/quest-app-backend/internal/auth/model"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	mediaModel "github.com/superhorsy/quest-app-backend/internal/media/model"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"github.com/superhorsy/quest-app-backend/internal/users/model"
+	"go.uber.org/zap"
+)
+
+const (
+	// ErrWrongPassword is returned when the password re-confirmation of the user fails.
+	ErrWrongPassword = errors.Error("wrong_password: password is incorrect")
+	// ErrInvalidAvatar is returned when the avatar isn't an image uploaded by the user.
+	ErrInvalidAvatar = errors.Error("invalid_avatar: avatar must be an image uploaded by you")
+)
+
+type DeleteProfileRequest struct {
+	Password string `json:"password"`
+}
+
+type SetAvatarRequest struct {
+	MediaID string `json:"media_id"`
+}
+
+// profileExport represents all personal data of the user.
+type profileExport struct {
+	Profile     *model.User                 `json:"profile"`
+	Quests      []questModel.QuestWithSteps `json:"quests"`
+	Assignments []questModel.Assignment     `json:"assignments"`
+	Media       []mediaModel.MediaRecord    `json:"media"`
+}
+
+// SessionResponse represents a device the user is signed in on.
+type SessionResponse struct {
+	authModel.Session
+	Current bool `json:"current"`
+}
+
+func (s *Server) getSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userId := ctx.Value(ContextUserIdKey).(string)
+	sessionId, _ := ctx.Value(ContextSessionIdKey).(string)
+
+	sessions, err := s.auth.GetSessions(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get sessions", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	res := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, SessionResponse{Session: session, Current: *session.ID == sessionId})
+	}
+
+	handleResponse(ctx, w, res)
+}
+
+func (s *Server) revokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userId := ctx.Value(ContextUserIdKey).(string)
+
+	if err := s.auth.RevokeSession(ctx, userId, mux.Vars(r)["id"]); err != nil {
+		logging.From(ctx).Error("failed to revoke session", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+type CreateTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedTokenResponse represents a new personal access token, its value is shown only once.
+type CreatedTokenResponse struct {
+	*authModel.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+func (s *Server) getTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tokens, err := s.auth.GetPersonalAccessTokens(ctx, ctx.Value(ContextUserIdKey).(string))
+	if err != nil {
+		logging.From(ctx).Error("failed to get personal access tokens", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, tokens)
+}
+
+func (s *Server) createToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, CreateTokenRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	t, token, err := s.auth.CreatePersonalAccessToken(ctx, ctx.Value(ContextUserIdKey).(string), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		logging.From(ctx).Error("failed to create personal access token", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, CreatedTokenResponse{PersonalAccessToken: t, Token: token})
+}
+
+func (s *Server) revokeToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.auth.RevokePersonalAccessToken(ctx, ctx.Value(ContextUserIdKey).(string), mux.Vars(r)["id"]); err != nil {
+		logging.From(ctx).Error("failed to revoke personal access token", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+// setAvatar shows an image the user uploaded via /media/upload as their avatar.
+func (s *Server) setAvatar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userId := ctx.Value(ContextUserIdKey).(string)
+
+	req, err := parseBodyIntoStruct(r, SetAvatarRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	media, err := s.media.GetMedia(ctx, req.MediaID)
+	if err != nil && !errors.Is(err, errors.ErrNotFound) {
+		logging.From(ctx).Error("failed to fetch media", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+	if media == nil || media.Owner == nil || *media.Owner != userId || media.Type != mediaModel.Image {
+		handleError(ctx, w, ErrInvalidAvatar.Wrap(errors.ErrValidation))
+		return
+	}
+
+	u, err := s.users.SetAvatar(ctx, userId, &media.ID)
+	if err != nil {
+		logging.From(ctx).Error("failed to set avatar", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, u)
+}
+
+func (s *Server) deleteAvatar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.users.SetAvatar(ctx, ctx.Value(ContextUserIdKey).(string), nil)
+	if err != nil {
+		logging.From(ctx).Error("failed to delete avatar", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, u)
+}
+
+// deleteProfile deletes the account of the user. The quests of the user are deleted even if they were already sent,
+// so recipients lose access to them, and the progress on quests sent to the user's email is deleted too.
+func (s *Server) deleteProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userId := ctx.Value(ContextUserIdKey).(string)
+
+	req, err := parseBodyIntoStruct(r, DeleteProfileRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	u, err := s.users.GetUser(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	// The password is guessed against the same lockout as logins
+	ip := clientIP(r)
+	if err := s.auth.CheckLoginAllowed(ctx, *u.Email, ip); err != nil {
+		logging.From(ctx).Error("password confirmation locked out", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	ok, err := s.users.CheckPassword(ctx, u, req.Password)
+	if err != nil {
+		logging.From(ctx).Error("failed to confirm password", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+	if err := s.auth.RecordLoginAttempt(ctx, *u.Email, u.ID, ip, ok); err != nil {
+		logging.From(ctx).Error("failed to record login attempt", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+	if !ok {
+		handleError(ctx, w, ErrWrongPassword.Wrap(errors.ErrForbidden))
+		return
+	}
+
+	// Listed before the records are deleted together with the user, the files are removed afterwards. Media linked
+	// from quests of other users is kept for them
+	media, err := s.media.GetUnsharedUserMedia(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user media", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	// Quests, media records, sessions, tokens, linked identities and the progress on quests sent to the user's
+	// email are deleted together with the user in one transaction
+	if err := s.users.DeleteUser(ctx, userId); err != nil {
+		logging.From(ctx).Error("failed to delete user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	s.media.DeleteFiles(ctx, media)
+
+	handleResponse(ctx, w, deletedUserResponse{Success: true})
+}
+
+// exportProfile returns all personal data of the user, as a zip archive including the uploaded files
+// or as JSON with ?format=json.
+func (s *Server) exportProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userId := ctx.Value(ContextUserIdKey).(string)
+
+	u, err := s.users.GetUser(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	quests, err := s.quests.GetUserQuests(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user quests", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	assignments, err := s.quests.GetUserAssignments(ctx, *u.Email)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user assignments", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	media, err := s.media.GetUserMedia(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user media", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	export := profileExport{
+		Profile:     u,
+		Quests:      quests,
+		Assignments: assignments,
+		Media:       media,
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		handleResponse(ctx, w, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="questy-export-%s.zip"`, time.Now().UTC().Format("2006-01-02")))
+
+	// Headers are already sent once the archive is being written, so failures can only be logged
+	if err := s.writeProfileExport(r, w, &export); err != nil {
+		logging.From(ctx).Error("failed to write profile export", zap.Error(err))
+	}
+}
+
+func (s *Server) writeProfileExport(r *http.Request, w io.Writer, export *profileExport) error {
+	ctx := r.Context()
+
+	zw := zip.NewWriter(w)
+
+	documents := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"quests.json", export.Quests},
+		{"assignments.json", export.Assignments},
+		{"media.json", export.Media},
+	}
+	for _, d := range documents {
+		f, err := zw.Create(d.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d.data); err != nil {
+			return err
+		}
+	}
+
+	for i := range export.Media {
+		src, err := s.media.OpenFile(ctx, &export.Media[i])
+		if err != nil {
+			logging.From(ctx).Error("failed to open media file", zap.String("id", export.Media[i].ID), zap.Error(err))
+			continue
+		}
+
+		f, err := zw.Create(path.Join("media", export.Media[i].Filename))
+		if err == nil {
+			_, err = io.Copy(f, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
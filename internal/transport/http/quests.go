@@ -9,10 +9,9 @@ import (
 	"github.com/superhorsy/quest-app-backend/internal/core/logging"
 	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
 	"go.uber.org/zap"
-	"html"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
 	"strconv"
 )
 
@@ -90,8 +89,14 @@ func (s *Server) getQuestsByUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	state := questModel.State(r.URL.Query().Get("state"))
+	if state != "" && !state.IsValid() {
+		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid state")))
+		return
+	}
+
 	userId := ctx.Value(ContextUserIdKey)
-	quests, meta, err := s.quests.GetQuestsByUser(ctx, userId.(string), offset, limit)
+	quests, meta, err := s.quests.GetQuestsByUser(ctx, userId.(string), state, offset, limit)
 	if err != nil {
 		logging.From(ctx).Error("failed to fetch quests", zap.Error(err))
 		handleError(ctx, w, err)
@@ -101,6 +106,54 @@ func (s *Server) getQuestsByUser(w http.ResponseWriter, r *http.Request) {
 	handleResponseWithMeta(ctx, w, quests, meta)
 }
 
+// cloneQuest copies the quest with its steps into a new draft, with copy_media the images and sounds of the steps
+// are copied too, so the copy keeps working when the originals are deleted.
+func (s *Server) cloneQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, questModel.CloneQuestRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	quest, err := s.quests.CloneQuest(ctx, mux.Vars(r)["id"], req.Name, req.CopyMedia)
+	if err != nil {
+		logging.From(ctx).Error("failed to clone quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+// publishQuest validates the quest and makes it available for sending.
+func (s *Server) publishQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quest, err := s.quests.PublishQuest(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to publish quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+func (s *Server) archiveQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quest, err := s.quests.ArchiveQuest(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to archive quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
 func (s *Server) getAvailableQuests(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -191,6 +244,20 @@ func (s *Server) sendQuest(w http.ResponseWriter, r *http.Request) {
 	}
 	sendRequest.QuestId = id
 
+	userId := ctx.Value(ContextUserIdKey).(string)
+
+	// Send to a contact from the address book
+	if sendRequest.ContactId != nil {
+		contact, err := s.contacts.GetContact(ctx, userId, *sendRequest.ContactId)
+		if err != nil {
+			logging.From(ctx).Error("failed to find contact", zap.Error(err))
+			handleError(ctx, w, err)
+			return
+		}
+		sendRequest.Email = *contact.Email
+		sendRequest.Name = *contact.Name
+	}
+
 	quest, err := s.quests.GetQuest(ctx, id)
 	if err != nil {
 		logging.From(ctx).Error("failed to send quest", zap.Error(err))
@@ -213,37 +280,34 @@ func (s *Server) sendQuest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Remember the recipient for the next time, failing to do so doesn't fail the sending
+	if err := s.contacts.SaveRecipient(ctx, userId, sendRequest.Email, sendRequest.Name); err != nil {
+		logging.From(ctx).Error("failed to save recipient to contacts", zap.Error(err))
+	}
+
 	// Get user
-	userId := ctx.Value(ContextUserIdKey)
-	user, err := s.users.GetUser(ctx, userId.(string))
+	user, err := s.users.GetUser(ctx, userId)
 	if err != nil {
 		logging.From(ctx).Error("failed to find user", zap.Error(err))
 		handleError(ctx, w, err)
 		return
 	}
 
-	mailingEnabled := os.Getenv("MAILING_ENABLED")
-	if mailingEnabled == "true" {
-		// Send email
-		go func() {
-			templateData := struct {
-				Name string
-				URL  string
-				IMG  string
-			}{
-				Name: html.EscapeString(sendRequest.Name),
-				URL:  "https://questy.fun",
-				IMG:  "https://questy.fun/files/10d26a38-2fdf-4f48-adff-3e052e7466f5.png",
-			}
-			subject := fmt.Sprintf("Ваш друг %s отправил вам квест на Questy.fun!", user.FullName())
-			err := helpers.SendEmail(sendRequest.Email, subject, "config/quest_invite.gohtml", templateData)
-			if err != nil {
-				logging.From(ctx).Error("failed to send email", zap.Error(err))
-				return
-			}
-		}()
+	// The link lets the recipient play right away, without registering
+	guestToken, err := helpers.CreateGuestToken(id, sendRequest.Email, helpers.GetConfig(ctx).Auth.GuestLinkTTL)
+	if err != nil {
+		logging.From(ctx).Error("failed to create guest token", zap.Error(err))
+		handleError(ctx, w, errors.ErrUnknown.Wrap(err))
+		return
 	}
 
+	// Send email
+	subject := fmt.Sprintf("Ваш друг %s отправил вам квест на Questy.fun!", user.FullName())
+	sendEmail(ctx, sendRequest.Email, subject, "config/quest_invite.gohtml", mailTemplateData{
+		Name: sendRequest.Name,
+		URL:  fmt.Sprintf("%s/play?token=%s", helpers.GetConfig(ctx).AppURL, url.QueryEscape(guestToken)),
+	})
+
 	handleResponse(ctx, w, struct {
 		Success bool `json:"success"`
 	}{Success: true})
@@ -324,3 +388,97 @@ func (s *Server) status(w http.ResponseWriter, r *http.Request) {
 
 	handleResponse(ctx, w, ql)
 }
+
+type ClaimQuestRequest struct {
+	Token string `json:"token"`
+}
+
+func (s *Server) startGuestQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	questId := mux.Vars(r)["id"]
+	email := ctx.Value(ContextGuestEmailKey).(string)
+
+	ql, err := s.quests.StartGuestQuest(ctx, questId, email)
+	if err != nil {
+		logging.From(ctx).Error("failed to start quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, ql)
+}
+
+func (s *Server) checkGuestAnswer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	questId := mux.Vars(r)["id"]
+	email := ctx.Value(ContextGuestEmailKey).(string)
+	answer, err := parseBodyIntoStruct(r, questModel.Answer{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	ql, err := s.quests.CheckGuestAnswer(ctx, questId, email, answer)
+	if err != nil {
+		logging.From(ctx).Error("failed to check answer", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, ql)
+}
+
+func (s *Server) guestStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	questId := mux.Vars(r)["id"]
+	email := ctx.Value(ContextGuestEmailKey).(string)
+
+	ql, err := s.quests.GetGuestAssignment(ctx, questId, email)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, ql)
+}
+
+// claimQuest moves the quest played as a guest, together with the progress on it, to the signed-in user.
+func (s *Server) claimQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, ClaimQuestRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	questId, guestEmail, err := helpers.ParseGuestToken(req.Token)
+	if err != nil {
+		logging.From(ctx).Error("failed to parse guest token", zap.Error(err))
+		handleError(ctx, w, ErrInvalidGuestToken.Wrap(errors.ErrValidation))
+		return
+	}
+
+	user, err := s.users.GetUser(ctx, ctx.Value(ContextUserIdKey).(string))
+	if err != nil {
+		logging.From(ctx).Error("failed to find user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	if err := s.quests.ClaimAssignment(ctx, questId, guestEmail, *user.Email); err != nil {
+		logging.From(ctx).Error("failed to claim quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	ql, err := s.quests.GetAssignment(ctx, questId)
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, ql)
+}
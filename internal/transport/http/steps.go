@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"go.uber.org/zap"
+)
+
+type ReorderStepsRequest struct {
+	StepIDs []string `json:"step_ids"`
+}
+
+func (s *Server) createStep(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	step, err := parseBodyIntoStruct(r, questModel.Step{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	createdStep, err := s.quests.CreateStep(ctx, mux.Vars(r)["id"], step)
+	if err != nil {
+		logging.From(ctx).Error("failed to create step", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, createdStep)
+}
+
+func (s *Server) updateStep(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+
+	step, err := parseBodyIntoStruct(r, questModel.Step{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	stepId := vars["stepId"]
+	step.ID = &stepId
+
+	updatedStep, err := s.quests.UpdateStep(ctx, vars["id"], step)
+	if err != nil {
+		logging.From(ctx).Error("failed to update step", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, updatedStep)
+}
+
+func (s *Server) deleteStep(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+
+	if err := s.quests.DeleteStep(ctx, vars["id"], vars["stepId"]); err != nil {
+		logging.From(ctx).Error("failed to delete step", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+// reorderSteps puts the steps of the quest into the order of step_ids, which must list every step exactly once.
+func (s *Server) reorderSteps(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, ReorderStepsRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	steps, err := s.quests.ReorderSteps(ctx, mux.Vars(r)["id"], req.StepIDs)
+	if err != nil {
+		logging.From(ctx).Error("failed to reorder steps", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, steps)
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"go.uber.org/zap"
+)
+
+type CreateQuestFromTemplateRequest struct {
+	TemplateID string            `json:"template_id"`
+	Variables  map[string]string `json:"variables"`
+}
+
+// getTemplates lists the template library, only the templates of a theme with ?theme=.
+func (s *Server) getTemplates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	theme := questModel.Theme(r.URL.Query().Get("theme"))
+	if theme != "" && !theme.IsValid() {
+		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid theme")))
+		return
+	}
+
+	templates, err := s.quests.GetTemplates(ctx, theme)
+	if err != nil {
+		logging.From(ctx).Error("failed to get templates", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, templates)
+}
+
+func (s *Server) getTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	t, err := s.quests.GetTemplate(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to get template", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, t)
+}
+
+// createQuestFromTemplate creates a new draft from a template with the variables filled in.
+func (s *Server) createQuestFromTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, CreateQuestFromTemplateRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	quest, err := s.quests.CreateQuestFromTemplate(ctx, req.TemplateID, req.Variables)
+	if err != nil {
+		logging.From(ctx).Error("failed to create quest from template", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+func (s *Server) createTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	t, err := parseBodyIntoStruct(r, questModel.Template{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	created, err := s.quests.CreateTemplate(ctx, t)
+	if err != nil {
+		logging.From(ctx).Error("failed to create template", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, created)
+}
+
+func (s *Server) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	t, err := parseBodyIntoStruct(r, questModel.Template{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+	t.ID = mux.Vars(r)["id"]
+
+	updated, err := s.quests.UpdateTemplate(ctx, t)
+	if err != nil {
+		logging.From(ctx).Error("failed to update template", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, updated)
+}
+
+func (s *Server) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := s.quests.DeleteTemplate(ctx, mux.Vars(r)["id"]); err != nil {
+		logging.From(ctx).Error("failed to delete template", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
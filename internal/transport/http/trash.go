@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"go.uber.org/zap"
+)
+
+func (s *Server) getTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	trash, err := s.quests.GetTrash(ctx, ctx.Value(ContextUserIdKey).(string))
+	if err != nil {
+		logging.From(ctx).Error("failed to get trash", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, trash)
+}
+
+func (s *Server) restoreQuest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	quest, err := s.quests.RestoreQuest(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to restore quest", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, quest)
+}
+
+func (s *Server) restoreStep(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+
+	step, err := s.quests.RestoreStep(ctx, vars["id"], vars["stepId"])
+	if err != nil {
+		logging.From(ctx).Error("failed to restore step", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, step)
+}
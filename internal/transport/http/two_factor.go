@@ -0,0 +1,189 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	"github.com/superhorsy/quest-app-backend/internal/users/model"
+	"go.uber.org/zap"
+)
+
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// MFARequiredResponse is returned by the login instead of the tokens when the user has two-factor authentication enabled.
+type MFARequiredResponse struct {
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// RecoveryCodesResponse represents new recovery codes, they are shown only once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// completeLogin will issue the tokens for a user who passed the first login step,
+// or ask for the second factor if the user has two-factor authentication enabled.
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, user *model.User) {
+	ctx := r.Context()
+
+	enabled, err := s.auth.IsTwoFactorEnabled(ctx, *user.ID)
+	if err != nil {
+		logging.From(ctx).Error("failed to check two-factor authentication", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	if enabled {
+		challenge, err := s.auth.CreateMFAChallenge(ctx, *user.ID)
+		if err != nil {
+			logging.From(ctx).Error("failed to create login challenge", zap.Error(err))
+			handleError(ctx, w, err)
+			return
+		}
+
+		handleResponse(ctx, w, MFARequiredResponse{MFARequired: true, ChallengeToken: challenge})
+		return
+	}
+
+	tokens, err := s.auth.IssueTokens(ctx, *user.ID, clientInfo(r))
+	if err != nil {
+		logging.From(ctx).Error("failed to create token", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleTokenResponse(ctx, w, user, tokens)
+}
+
+func (s *Server) verifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, VerifyTwoFactorRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	userId, err := s.auth.VerifyMFAChallenge(ctx, req.ChallengeToken, req.Code, req.RecoveryCode, clientIP(r))
+	if err != nil {
+		logging.From(ctx).Error("failed to verify second factor", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	user, err := s.users.GetUser(ctx, userId)
+	if err != nil {
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	tokens, err := s.auth.IssueTokens(ctx, userId, clientInfo(r))
+	if err != nil {
+		logging.From(ctx).Error("failed to create token", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleTokenResponse(ctx, w, user, tokens)
+}
+
+func (s *Server) getTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status, err := s.auth.GetTwoFactorStatus(ctx, ctx.Value(ContextUserIdKey).(string))
+	if err != nil {
+		logging.From(ctx).Error("failed to get two-factor authentication status", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, status)
+}
+
+func (s *Server) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, err := s.users.GetUser(ctx, ctx.Value(ContextUserIdKey).(string))
+	if err != nil {
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	enrollment, err := s.auth.EnrollTOTP(ctx, *user.ID, *user.Email)
+	if err != nil {
+		logging.From(ctx).Error("failed to enroll two-factor authentication", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, enrollment)
+}
+
+func (s *Server) confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, TwoFactorCodeRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	codes, err := s.auth.ConfirmTOTP(ctx, ctx.Value(ContextUserIdKey).(string), req.Code)
+	if err != nil {
+		logging.From(ctx).Error("failed to confirm two-factor authentication", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (s *Server) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, TwoFactorCodeRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	if err := s.auth.DisableTOTP(ctx, ctx.Value(ContextUserIdKey).(string), req.Code, req.RecoveryCode, clientIP(r)); err != nil {
+		logging.From(ctx).Error("failed to disable two-factor authentication", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, struct {
+		Success bool `json:"success"`
+	}{Success: true})
+}
+
+func (s *Server) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, TwoFactorCodeRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	codes, err := s.auth.RegenerateRecoveryCodes(ctx, ctx.Value(ContextUserIdKey).(string), req.Code, clientIP(r))
+	if err != nil {
+		logging.From(ctx).Error("failed to regenerate recovery codes", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, RecoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
 	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
 	"github.com/superhorsy/quest-app-backend/internal/users/model"
 	"go.uber.org/zap"
 )
@@ -18,6 +21,28 @@ type searchUsersRequest struct {
 	Limit   int64          `json:"limit"`
 }
 
+const (
+	// searchUsersDefaultLimit is the page size of the user search when no limit is given.
+	searchUsersDefaultLimit = 20
+	// searchUsersMaxLimit caps the page size of the user search, so users can't be enumerated in bulk.
+	searchUsersMaxLimit = 50
+)
+
+const (
+	// profileQuestsDefaultLimit is the page size of the quests on a public profile when no limit is given.
+	profileQuestsDefaultLimit = 20
+	// profileQuestsMaxLimit caps the page size of the quests on a public profile.
+	profileQuestsMaxLimit = 50
+)
+
+// PublicProfileResponse represents what everyone can see about a user.
+type PublicProfileResponse struct {
+	*model.PublicUser
+	AvatarURL *string                   `json:"avatar_url"`
+	Stats     *questModel.AuthorStats   `json:"stats"`
+	Quests    []questModel.QuestSummary `json:"quests"`
+}
+
 type deletedUserResponse struct {
 	Success bool `json:"success"`
 }
@@ -125,6 +150,63 @@ func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) {
 	handleResponse(ctx, w, users)
 }
 
+// searchPublicUsers finds users by nickname and names ranked by similarity, or by their exact email.
+// Emails of found users are never returned, and a partial email doesn't match anyone.
+func (s *Server) searchPublicUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+
+	var filters []model.Filter
+	if email := strings.TrimSpace(query.Get("email")); email != "" {
+		filters = append(filters, model.Filter{MatchType: model.MatchTypeEqual, Field: model.FieldEmail, Value: email})
+	}
+	for _, field := range []model.Field{model.FieldNickname, model.FieldFirstName, model.FieldLastName} {
+		if v := strings.TrimSpace(query.Get(string(field))); v != "" {
+			filters = append(filters, model.Filter{MatchType: model.MatchTypeSimilar, Field: field, Value: v})
+		}
+	}
+	if len(filters) == 0 {
+		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("at least one of email, nickname, first_name, last_name is required")))
+		return
+	}
+
+	var limit, offset int64 = searchUsersDefaultLimit, 0
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.ParseInt(v, 10, 64); err != nil || limit < 1 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid limit")))
+			return
+		}
+	}
+	if limit > searchUsersMaxLimit {
+		limit = searchUsersMaxLimit
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.ParseInt(v, 10, 64); err != nil || offset < 0 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid offset")))
+			return
+		}
+	}
+
+	users, err := s.users.FindUsers(ctx, filters, offset, limit)
+	if err != nil && !errors.Is(err, errors.ErrNotFound) {
+		logging.From(ctx).Error("failed to search users", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	found := []*model.PublicUser{}
+	for _, u := range users {
+		if u.IsDisabled() {
+			continue
+		}
+		found = append(found, u.Public())
+	}
+
+	handleResponse(ctx, w, found)
+}
+
 func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -139,3 +221,68 @@ func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
 
 	handleResponse(ctx, w, deletedUserResponse{Success: true})
 }
+
+// getPublicProfile shows the public part of the profile of a user together with their published quests.
+// The quests are paginated with offset and limit, the total count is returned in meta.
+func (s *Server) getPublicProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+
+	limit, offset := profileQuestsDefaultLimit, 0
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid limit")))
+			return
+		}
+	}
+	if limit > profileQuestsMaxLimit {
+		limit = profileQuestsMaxLimit
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid offset")))
+			return
+		}
+	}
+
+	u, err := s.users.GetUserByNickname(ctx, mux.Vars(r)["nickname"])
+	if err != nil {
+		logging.From(ctx).Error("failed to get user", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+	if u.IsDisabled() {
+		handleError(ctx, w, errors.ErrNotFound)
+		return
+	}
+
+	profile := PublicProfileResponse{PublicUser: u.Public()}
+
+	if u.AvatarID != nil {
+		// A missing avatar doesn't fail the profile
+		if avatar, err := s.media.GetMedia(ctx, *u.AvatarID); err != nil {
+			logging.From(ctx).Error("failed to fetch avatar", zap.Error(err))
+		} else {
+			profile.AvatarURL = &avatar.Link
+		}
+	}
+
+	profile.Stats, err = s.quests.GetAuthorStats(ctx, *u.ID)
+	if err != nil {
+		logging.From(ctx).Error("failed to get author stats", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	quests, meta, err := s.quests.GetPublishedQuests(ctx, *u.ID, offset, limit)
+	if err != nil {
+		logging.From(ctx).Error("failed to fetch quests", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+	profile.Quests = quests
+
+	handleResponseWithMeta(ctx, w, profile, meta)
+}
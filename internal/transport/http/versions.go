@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"github.com/superhorsy/quest-app-backend/internal/core/logging"
+	questModel "github.com/superhorsy/quest-app-backend/internal/quests/model"
+	"go.uber.org/zap"
+)
+
+func (s *Server) getVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	versions, err := s.quests.GetVersions(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		logging.From(ctx).Error("failed to get quest versions", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, versions)
+}
+
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		handleError(ctx, w, errors.ErrInvalidRequest.Wrap(errors.New("invalid version")))
+		return
+	}
+
+	v, err := s.quests.GetVersion(ctx, vars["id"], version)
+	if err != nil {
+		logging.From(ctx).Error("failed to get quest version", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, v)
+}
+
+// migrateRecipients moves recipients of the quest to its current content, keeping their progress.
+func (s *Server) migrateRecipients(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseBodyIntoStruct(r, questModel.MigrateRecipientsRequest{})
+	if err != nil {
+		handleError(ctx, w, err)
+		return
+	}
+
+	res, err := s.quests.MigrateRecipients(ctx, mux.Vars(r)["id"], req.Emails)
+	if err != nil {
+		logging.From(ctx).Error("failed to migrate recipients", zap.Error(err))
+		handleError(ctx, w, err)
+		return
+	}
+
+	handleResponse(ctx, w, res)
+}
@@ -13,20 +13,78 @@ type UserWithPass struct {
 
 // User represents a person using our platform.
 type User struct {
-	ID        *string    `json:"id" db:"id"`
-	FirstName *string    `json:"first_name" db:"first_name"`
-	LastName  *string    `json:"last_name" db:"last_name"`
-	Nickname  *string    `json:"nickname" db:"nickname"`
-	Password  *string    `json:"-" db:"password"`
-	Email     *string    `json:"email" db:"email"`
-	CreatedAt *time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+	ID         *string    `json:"id" db:"id"`
+	FirstName  *string    `json:"first_name" db:"first_name"`
+	LastName   *string    `json:"last_name" db:"last_name"`
+	Nickname   *string    `json:"nickname" db:"nickname"`
+	Password   *string    `json:"-" db:"password"`
+	Email      *string    `json:"email" db:"email"`
+	Bio        *string    `json:"bio" db:"bio"`
+	AvatarID   *string    `json:"avatar_id" db:"avatar_id"`
+	Role       *Role      `json:"role" db:"role"`
+	VerifiedAt *time.Time `json:"verified_at" db:"verified_at"`
+	DisabledAt *time.Time `json:"disabled_at" db:"disabled_at"`
+	CreatedAt  *time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at" db:"updated_at"`
 }
 
 func (u *User) FullName() string {
 	return fmt.Sprintf("%s %s", *u.FirstName, *u.LastName)
 }
 
+// IsVerified reports whether the user has confirmed the ownership of their email address.
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+// IsDisabled reports whether the account was disabled by an admin.
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// Role is an enum providing what the user is allowed to do.
+type Role string
+
+const (
+	// RolePlayer represents a user who can only play quests sent to them.
+	RolePlayer Role = "player"
+	// RoleAuthor represents a user who can also create quests.
+	RoleAuthor Role = "author"
+	// RoleAdmin represents staff managing users and quests of everyone.
+	RoleAdmin Role = "admin"
+)
+
+// IsValid reports whether the role is one of the known roles.
+func (r Role) IsValid() bool {
+	switch r {
+	case RolePlayer, RoleAuthor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// PublicUser represents the part of a user visible to other users.
+type PublicUser struct {
+	ID        *string `json:"id"`
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Nickname  *string `json:"nickname"`
+	Bio       *string `json:"bio"`
+	AvatarID  *string `json:"avatar_id"`
+}
+
+// Public returns the part of the user visible to other users.
+func (u *User) Public() *PublicUser {
+	return &PublicUser{
+		ID:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Nickname:  u.Nickname,
+		Bio:       u.Bio,
+		AvatarID:  u.AvatarID,
+	}
+}
+
 // Field is an enum providing valid fields for filtering.
 type Field string
 
@@ -39,6 +97,8 @@ const (
 	FieldNickname Field = "nickname"
 	// FieldEmail represents the email field.
 	FieldEmail Field = "email"
+	// FieldRole represents the role field.
+	FieldRole Field = "role"
 )
 
 // MatchType is an enum providing valid matching mechanisms for filtering values.
@@ -49,6 +109,8 @@ const (
 	MatchTypeLike MatchType = "ILIKE"
 	// MatchTypeEqual represents an exact match.
 	MatchTypeEqual MatchType = "="
+	// MatchTypeSimilar represents a trigram word similarity match, results are ranked by the similarity.
+	MatchTypeSimilar MatchType = "%>"
 )
 
 // Filter is a struct representing a filter for finding users.
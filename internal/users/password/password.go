@@ -0,0 +1,203 @@
+// Package password checks passwords against the configured policy and hashes them with bcrypt or argon2id.
+package password
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/config"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// ErrPasswordTooShort is returned when the password is shorter than the configured minimum.
+	ErrPasswordTooShort = errors.Error("password_too_short: password is too short")
+	// ErrPasswordTooLong is returned when the password is longer than maxLength bytes.
+	ErrPasswordTooLong = errors.Error("password_too_long: password is too long")
+	// ErrPasswordBreached is returned when the password is on the list of breached passwords.
+	ErrPasswordBreached = errors.Error("password_breached: password is too common, choose another one")
+	// ErrMalformedHash is returned when a stored hash can't be parsed.
+	ErrMalformedHash = errors.Error("malformed_hash: password hash is malformed")
+)
+
+const (
+	// AlgorithmBcrypt hashes passwords with bcrypt.
+	AlgorithmBcrypt = "bcrypt"
+	// AlgorithmArgon2id hashes passwords with argon2id, stored in the PHC string format.
+	AlgorithmArgon2id = "argon2id"
+)
+
+const (
+	// maxLength is the most bcrypt takes into account, the same limit applies to argon2id,
+	// so the algorithm can be switched back and forth.
+	maxLength = 72
+	// argon2SaltLength and argon2KeyLength are the sizes recommended by RFC 9106.
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Policy checks new passwords and hashes them with the configured parameters.
+type Policy struct {
+	cfg      config.PasswordConfig
+	breached map[string]struct{}
+}
+
+// New will instantiate a new instance of Policy, reading the breached password list if one is configured.
+func New(cfg config.PasswordConfig) (*Policy, error) {
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+		if cfg.Argon2Time == 0 || cfg.Argon2Memory == 0 || cfg.Argon2Threads == 0 {
+			return nil, errors.New("argon2 time, memory and threads must be set")
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", cfg.Algorithm)
+	}
+
+	p := &Policy{cfg: cfg, breached: map[string]struct{}{}}
+	if cfg.BreachedListFile != "" {
+		if err := p.loadBreached(cfg.BreachedListFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Validate checks the password against the policy.
+func (p *Policy) Validate(password string) error {
+	if len([]rune(password)) < p.cfg.MinLength {
+		return ErrPasswordTooShort.Wrap(errors.ErrValidation)
+	}
+	if len(password) > maxLength {
+		return ErrPasswordTooLong.Wrap(errors.ErrValidation)
+	}
+	if _, ok := p.breached[password]; ok {
+		return ErrPasswordBreached.Wrap(errors.ErrValidation)
+	}
+
+	return nil
+}
+
+// Hash returns the hash of the password using the configured algorithm and parameters.
+func (p *Policy) Hash(password string) (string, error) {
+	if len(password) > maxLength {
+		return "", ErrPasswordTooLong.Wrap(errors.ErrValidation)
+	}
+
+	if p.cfg.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), p.cfg.BcryptCost)
+		if err != nil {
+			return "", errors.ErrUnknown.Wrap(err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.ErrUnknown.Wrap(err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.cfg.Argon2Time, p.cfg.Argon2Memory, p.cfg.Argon2Threads, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		p.cfg.Argon2Memory, p.cfg.Argon2Time, p.cfg.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks the password against a hash made by any of the supported algorithms.
+func (p *Policy) Verify(hash string, password string) (bool, error) {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, ErrMalformedHash.Wrap(errors.ErrUnknown.Wrap(err))
+		}
+		return true, nil
+	}
+
+	params, salt, key, err := parseArgon2(hash)
+	if err != nil {
+		return false, err
+	}
+	actual := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+// NeedsRehash checks if the hash was made with another algorithm or other parameters than the configured ones.
+func (p *Policy) NeedsRehash(hash string) bool {
+	if p.cfg.Algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != p.cfg.BcryptCost
+	}
+
+	params, _, _, err := parseArgon2(hash)
+	return err != nil || params != argon2Params{time: p.cfg.Argon2Time, memory: p.cfg.Argon2Memory, threads: p.cfg.Argon2Threads}
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// parseArgon2 reads a hash in the format $argon2id$v=19$m=65536,t=3,p=2$salt$key.
+func parseArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrMalformedHash.Wrap(errors.ErrUnknown)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrMalformedHash.Wrap(errors.ErrUnknown)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, ErrMalformedHash.Wrap(errors.ErrUnknown.Wrap(err))
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash.Wrap(errors.ErrUnknown.Wrap(err))
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash.Wrap(errors.ErrUnknown.Wrap(err))
+	}
+
+	return params, salt, key, nil
+}
+
+// loadBreached reads the list of breached passwords, one per line. Empty lines and lines starting with # are skipped.
+func (p *Policy) loadBreached(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[line] = struct{}{}
+	}
+
+	return scanner.Err()
+}
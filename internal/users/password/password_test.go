@@ -0,0 +1,227 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/superhorsy/quest-app-backend/internal/core/config"
+	"github.com/superhorsy/quest-app-backend/internal/core/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The parameters are kept low so the tests run fast.
+var (
+	bcryptConfig = config.PasswordConfig{MinLength: 8, Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+	argon2Config = config.PasswordConfig{MinLength: 8, Algorithm: AlgorithmArgon2id,
+		Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1}
+)
+
+func newPolicy(t *testing.T, cfg config.PasswordConfig) *Policy {
+	t.Helper()
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestHashAndVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.PasswordConfig
+		format *regexp.Regexp
+	}{
+		{
+			name:   "bcrypt",
+			cfg:    bcryptConfig,
+			format: regexp.MustCompile(`^\$2a\$04\$`),
+		},
+		{
+			name:   "argon2id",
+			cfg:    argon2Config,
+			format: regexp.MustCompile(`^\$argon2id\$v=19\$m=1024,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPolicy(t, tt.cfg)
+
+			hash, err := p.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.format.MatchString(hash) {
+				t.Errorf("unexpected hash format %q", hash)
+			}
+
+			if ok, err := p.Verify(hash, "correct horse"); err != nil || !ok {
+				t.Errorf("expected the password to match, got (%t, %v)", ok, err)
+			}
+			if ok, err := p.Verify(hash, "wrong horse"); err != nil || ok {
+				t.Errorf("expected the password not to match, got (%t, %v)", ok, err)
+			}
+
+			other, err := p.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other == hash {
+				t.Error("expected hashes of the same password to be salted differently")
+			}
+		})
+	}
+}
+
+func TestVerifyAcrossAlgorithms(t *testing.T) {
+	bcryptPolicy := newPolicy(t, bcryptConfig)
+	argon2Policy := newPolicy(t, argon2Config)
+
+	bcryptHash, err := bcryptPolicy.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2Hash, err := argon2Policy.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := argon2Policy.Verify(bcryptHash, "correct horse"); err != nil || !ok {
+		t.Errorf("expected argon2id policy to verify a bcrypt hash, got (%t, %v)", ok, err)
+	}
+	if ok, err := bcryptPolicy.Verify(argon2Hash, "correct horse"); err != nil || !ok {
+		t.Errorf("expected bcrypt policy to verify an argon2id hash, got (%t, %v)", ok, err)
+	}
+}
+
+func TestVerifyMalformedHash(t *testing.T) {
+	p := newPolicy(t, argon2Config)
+
+	for _, hash := range []string{
+		"",
+		"$argon2id$v=19$m=1024,t=1,p=1$salt",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+	} {
+		if _, err := p.Verify(hash, "correct horse"); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("expected %v for %q, got %v", ErrMalformedHash, hash, err)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHash, err := newPolicy(t, bcryptConfig).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2Hash, err := newPolicy(t, argon2Config).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(cfg config.PasswordConfig, change func(*config.PasswordConfig)) config.PasswordConfig {
+		change(&cfg)
+		return cfg
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.PasswordConfig
+		hash string
+		want bool
+	}{
+		{name: "same bcrypt cost", cfg: bcryptConfig, hash: bcryptHash},
+		{
+			name: "bcrypt cost raised",
+			cfg:  with(bcryptConfig, func(c *config.PasswordConfig) { c.BcryptCost++ }),
+			hash: bcryptHash,
+			want: true,
+		},
+		{name: "argon2id hash under bcrypt", cfg: bcryptConfig, hash: argon2Hash, want: true},
+		{name: "same argon2 parameters", cfg: argon2Config, hash: argon2Hash},
+		{
+			name: "argon2 time changed",
+			cfg:  with(argon2Config, func(c *config.PasswordConfig) { c.Argon2Time = 2 }),
+			hash: argon2Hash,
+			want: true,
+		},
+		{
+			name: "argon2 memory changed",
+			cfg:  with(argon2Config, func(c *config.PasswordConfig) { c.Argon2Memory = 2048 }),
+			hash: argon2Hash,
+			want: true,
+		},
+		{
+			name: "argon2 threads changed",
+			cfg:  with(argon2Config, func(c *config.PasswordConfig) { c.Argon2Threads = 2 }),
+			hash: argon2Hash,
+			want: true,
+		},
+		{name: "bcrypt hash under argon2id", cfg: argon2Config, hash: bcryptHash, want: true},
+		{name: "malformed hash", cfg: argon2Config, hash: "$argon2id$", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newPolicy(t, tt.cfg).NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(list, []byte("# common passwords\npassword1\r\n\nqwertyuiop\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := bcryptConfig
+	cfg.BreachedListFile = list
+	p := newPolicy(t, cfg)
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{name: "valid", password: "correct horse"},
+		{name: "too short", password: "short", want: ErrPasswordTooShort},
+		{name: "length counted in runes", password: "пароль12"},
+		{name: "too long", password: strings.Repeat("a", maxLength+1), want: ErrPasswordTooLong},
+		{name: "breached", password: "password1", want: ErrPasswordBreached},
+		{name: "breached after the comment", password: "qwertyuiop", want: ErrPasswordBreached},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Validate(tt.password)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidParameters(t *testing.T) {
+	for _, cfg := range []config.PasswordConfig{
+		{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost - 1},
+		{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MaxCost + 1},
+		{Algorithm: AlgorithmArgon2id, Argon2Time: 1, Argon2Memory: 1024},
+		{Algorithm: "md5"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -21,11 +21,20 @@ func (s *Store) FindUsers(ctx context.Context, filters []model.Filter, offset, l
 	}
 
 	var whereClauses []string
+	var rankClauses []string
 	var values []interface{}
 
 	for i, f := range filters {
 		whereClauses = append(whereClauses, fmt.Sprintf("%s %s $%d", f.Field, f.MatchType, i+1))
 		values = append(values, getFindValue(f))
+		if f.MatchType == model.MatchTypeSimilar {
+			rankClauses = append(rankClauses, fmt.Sprintf("word_similarity($%d, %s)", i+1, f.Field))
+		}
+	}
+
+	orderClause := "id ASC"
+	if len(rankClauses) > 0 {
+		orderClause = fmt.Sprintf("%s DESC, id ASC", strings.Join(rankClauses, " + "))
 	}
 
 	limitClause := ""
@@ -34,7 +43,7 @@ func (s *Store) FindUsers(ctx context.Context, filters []model.Filter, offset, l
 		limitClause = fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 	}
 
-	rows, err := s.db.QueryxContext(ctx, fmt.Sprintf("SELECT * FROM users WHERE %s ORDER BY id ASC%s", strings.Join(whereClauses, " AND "), limitClause), values...)
+	rows, err := s.db.QueryxContext(ctx, fmt.Sprintf("SELECT * FROM users WHERE %s ORDER BY %s%s", strings.Join(whereClauses, " AND "), orderClause, limitClause), values...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.ErrNotFound.Wrap(err)
@@ -65,6 +74,22 @@ func (s *Store) FindUsers(ctx context.Context, filters []model.Filter, offset, l
 	return users, nil
 }
 
+// ListUsers will retrieve users ordered by registration date using pagination if limit is gt 0.
+func (s *Store) ListUsers(ctx context.Context, offset, limit int64) ([]*model.User, error) {
+	limitClause := ""
+
+	if limit > 0 {
+		limitClause = fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	}
+
+	users := []*model.User{}
+	if err := s.db.SelectContext(ctx, &users, "SELECT * FROM users ORDER BY created_at ASC, id ASC"+limitClause); err != nil {
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return users, nil
+}
+
 func getFindValue(f model.Filter) string {
 	switch f.MatchType {
 	case model.MatchTypeLike:
@@ -46,9 +46,11 @@ var timeNow = func() *time.Time {
 type DB interface {
 	NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error)
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
 }
 
 // Store provides functionality for working with a database.
@@ -127,6 +129,21 @@ func (s *Store) GetUserByEmail(ctx context.Context, email string) (*model.User,
 	return &u, nil
 }
 
+// GetUserByNickname will retrieve an existing user via their nickname, ignoring the case.
+func (s *Store) GetUserByNickname(ctx context.Context, nickname string) (*model.User, error) {
+	var u model.User
+
+	if err := s.db.GetContext(ctx, &u, "SELECT * FROM users WHERE lower(nickname) = lower($1)", nickname); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.ErrNotFound.Wrap(err)
+		}
+
+		return nil, errors.ErrUnknown.Wrap(err)
+	}
+
+	return &u, nil
+}
+
 // UpdateUser will update an existing user in the database using only the present data provided.
 func (s *Store) UpdateUser(ctx context.Context, u *model.UserWithPass) (*model.User, error) {
 	if u.ID == nil || *u.ID == "" {
@@ -143,6 +160,7 @@ func (s *Store) UpdateUser(ctx context.Context, u *model.UserWithPass) (*model.U
 		nickname = COALESCE(:nickname, nickname), 
 		password = COALESCE(:password, password),
 		email = COALESCE(:email, email),
+		bio = COALESCE(:bio, bio),
 		updated_at = :updated_at 
 		WHERE id = :id
 		RETURNING *`, u)
@@ -164,19 +182,119 @@ func (s *Store) UpdateUser(ctx context.Context, u *model.UserWithPass) (*model.U
 	return updatedUser, nil
 }
 
-// DeleteUser will delete an existing user via their ID.
-func (s *Store) DeleteUser(ctx context.Context, id string) error {
-	res, err := s.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+// SetVerified will mark the email of the user as verified, if it wasn't changed since the verification was requested.
+func (s *Store) SetVerified(ctx context.Context, id string, email string) (*model.User, error) {
+	var u model.User
+
+	now := timeNow()
+	err := s.db.GetContext(ctx, &u,
+		`UPDATE users SET verified_at = COALESCE(verified_at, $1), updated_at = $1 
+		WHERE id = $2 AND email = $3 
+		RETURNING *`, now, id, email)
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) {
-			if pqErr.Code.Name() == pqErrInvalidTextRepresentation && strings.Contains(pqErr.Error(), "uuid") {
-				return ErrInvalidID.Wrap(errors.ErrValidation.Wrap(err))
-			}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotUpdated.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &u, nil
+}
+
+// SetRole will change the role of the user.
+func (s *Store) SetRole(ctx context.Context, id string, role model.Role) (*model.User, error) {
+	var u model.User
+
+	err := s.db.GetContext(ctx, &u,
+		`UPDATE users SET role = $1, updated_at = $2 WHERE id = $3 RETURNING *`, role, timeNow(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotUpdated.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &u, nil
+}
+
+// SetDisabled will disable the account of the user or enable it again.
+func (s *Store) SetDisabled(ctx context.Context, id string, disabled bool) (*model.User, error) {
+	var u model.User
+
+	now := timeNow()
+	var disabledAt *time.Time
+	if disabled {
+		disabledAt = now
+	}
+
+	err := s.db.GetContext(ctx, &u,
+		`UPDATE users SET disabled_at = $1, updated_at = $2 WHERE id = $3 RETURNING *`, disabledAt, now, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotUpdated.Wrap(errors.ErrNotFound.Wrap(err))
 		}
+		return nil, checkWriteError(err)
+	}
+
+	return &u, nil
+}
 
+// SetAvatar will set the media record shown as the avatar of the user, nil removes the avatar.
+func (s *Store) SetAvatar(ctx context.Context, id string, avatarId *string) (*model.User, error) {
+	var u model.User
+
+	err := s.db.GetContext(ctx, &u,
+		`UPDATE users SET avatar_id = $1, updated_at = $2 WHERE id = $3 RETURNING *`, avatarId, timeNow(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotUpdated.Wrap(errors.ErrNotFound.Wrap(err))
+		}
+		return nil, checkWriteError(err)
+	}
+
+	return &u, nil
+}
+
+// SetPassword will replace the password hash of the user, unless the password was changed in the meantime.
+func (s *Store) SetPassword(ctx context.Context, id string, oldHash string, newHash string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE users SET password = $1 WHERE id = $2 AND password = $3`, newHash, id, oldHash)
+
+	return checkWriteError(err)
+}
+
+// DeleteUser will delete an existing user via their ID in one transaction with everything referencing it. Quests,
+// media records, sessions, tokens and linked identities go via their foreign keys. The assignments of the user's
+// quests, which restrict deleting a sent quest, and the progress on quests sent to the user's email, which only
+// references the email, are removed explicitly.
+func (s *Store) DeleteUser(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
 		return errors.ErrUnknown.Wrap(err)
 	}
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM quest_to_email
+				WHERE quest_id IN (SELECT id FROM quests WHERE owner = $1)
+				OR email = (SELECT email FROM users WHERE id = $1)`, id)
+	if err != nil {
+		return deleteError(err)
+	}
+
+	// Media linked from quests of other users, like clones or quests the user edited, is kept without an owner
+	_, err = tx.ExecContext(ctx,
+		`UPDATE media SET owner = NULL
+				WHERE owner = $1
+				AND link IN (SELECT s.question_content FROM steps s JOIN quests q ON q.id = s.quest_id WHERE q.owner <> $1)`, id)
+	if err != nil {
+		return deleteError(err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return deleteError(err)
+	}
 	rows, err := res.RowsAffected()
 	if err != nil {
 		return errors.ErrUnknown.Wrap(err)
@@ -185,9 +303,24 @@ func (s *Store) DeleteUser(ctx context.Context, id string) error {
 		return ErrUserNotDeleted.Wrap(errors.ErrNotFound)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return errors.ErrUnknown.Wrap(err)
+	}
+
 	return nil
 }
 
+func deleteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code.Name() == pqErrInvalidTextRepresentation && strings.Contains(pqErr.Error(), "uuid") {
+			return ErrInvalidID.Wrap(errors.ErrValidation.Wrap(err))
+		}
+	}
+
+	return errors.ErrUnknown.Wrap(err)
+}
+
 //nolint:cyclop
 func checkWriteError(err error) error {
 	if err == nil {
@@ -5,12 +5,11 @@ package users
 import (
 	"context"
 
-	"github.com/superhorsy/quest-app-backend/internal/core/helpers"
-
 	"github.com/superhorsy/quest-app-backend/internal/core/errors"
 	"github.com/superhorsy/quest-app-backend/internal/core/logging"
 	"github.com/superhorsy/quest-app-backend/internal/events"
 	"github.com/superhorsy/quest-app-backend/internal/users/model"
+	"github.com/superhorsy/quest-app-backend/internal/users/password"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +20,8 @@ const (
 	ErrInvalidFilterMatchType = errors.Error("invalid_filter_match_type: invalid filter match type")
 	// ErrInvalidFilterField is returned when a filter field is not found in the supported enum list.
 	ErrInvalidFilterField = errors.Error("invalid_filter_field: invalid filter field")
+	// ErrInvalidRole is returned when the role is not found in the supported enum list.
+	ErrInvalidRole = errors.Error("invalid_role: invalid role")
 )
 
 // Store represents a type for storing a user in a database.
@@ -29,8 +30,23 @@ type Store interface {
 	UpdateUser(ctx context.Context, user *model.UserWithPass) (*model.User, error)
 	GetUser(ctx context.Context, id string) (*model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserByNickname(ctx context.Context, nickname string) (*model.User, error)
 	FindUsers(ctx context.Context, filters []model.Filter, offset, limit int64) ([]*model.User, error)
+	ListUsers(ctx context.Context, offset, limit int64) ([]*model.User, error)
 	DeleteUser(ctx context.Context, id string) error
+	SetVerified(ctx context.Context, id string, email string) (*model.User, error)
+	SetRole(ctx context.Context, id string, role model.Role) (*model.User, error)
+	SetDisabled(ctx context.Context, id string, disabled bool) (*model.User, error)
+	SetAvatar(ctx context.Context, id string, avatarId *string) (*model.User, error)
+	SetPassword(ctx context.Context, id string, oldHash string, newHash string) error
+}
+
+// Passwords represents a type for checking new passwords against the policy and hashing them.
+type Passwords interface {
+	Validate(password string) error
+	Hash(password string) (string, error)
+	Verify(hash string, password string) (bool, error)
+	NeedsRehash(hash string) bool
 }
 
 // Events represents a type for producing events on user CRUD operations.
@@ -40,8 +56,11 @@ type Events interface {
 
 // Users provides functionality for CRUD operations on a user.
 type Users struct {
-	store  Store
-	events Events
+	store     Store
+	passwords Passwords
+	events    Events
+	// dummyHash is checked when no user has the email, so unknown emails take as long as a wrong password
+	dummyHash string
 }
 
 // Quests provides functionality for CRUD operations on quests.
@@ -60,16 +79,29 @@ func (u *Users) GetUserByEmail(ctx context.Context, email string) (*model.User,
 }
 
 // New will instantiate a new instance of Users.
-func New(s Store, e Events) *Users {
-	return &Users{
-		store:  s,
-		events: e,
+func New(s Store, p Passwords, e Events) (*Users, error) {
+	dummyHash, err := p.Hash("dummy password")
+	if err != nil {
+		return nil, err
 	}
+
+	return &Users{
+		store:     s,
+		passwords: p,
+		events:    e,
+		dummyHash: dummyHash,
+	}, nil
 }
 
 // CreateUser will try to create a user in our database with the provided data if it represents a unique new user.
 func (u *Users) CreateUser(ctx context.Context, user *model.UserWithPass) (*model.User, error) {
-	passwordHash := helpers.HashAndSalt([]byte(*user.Password))
+	if user.Password == nil {
+		return nil, password.ErrPasswordTooShort.Wrap(errors.ErrValidation)
+	}
+	passwordHash, err := u.hashPassword(*user.Password)
+	if err != nil {
+		return nil, err
+	}
 	user.Password = &passwordHash
 
 	// Not much validation needed before storing in the database as the database itself is handling most of that
@@ -97,7 +129,10 @@ func (u *Users) CreateUser(ctx context.Context, user *model.UserWithPass) (*mode
 // UpdateUser will try to update an existing user in our database with the provided data.
 func (u *Users) UpdateUser(ctx context.Context, user *model.UserWithPass) (*model.User, error) {
 	if user.Password != nil && *user.Password != "" {
-		passwordHash := helpers.HashAndSalt([]byte(*user.Password))
+		passwordHash, err := u.hashPassword(*user.Password)
+		if err != nil {
+			return nil, err
+		}
 		user.Password = &passwordHash
 	}
 
@@ -115,6 +150,145 @@ func (u *Users) UpdateUser(ctx context.Context, user *model.UserWithPass) (*mode
 	return updatedUser, nil
 }
 
+// CheckPassword verifies the password of the user, a nil user never matches but takes as long to check.
+// Passwords hashed with outdated parameters are rehashed once they match.
+func (u *Users) CheckPassword(ctx context.Context, user *model.User, pass string) (bool, error) {
+	hash := u.dummyHash
+	if user != nil {
+		hash = *user.Password
+	}
+
+	ok, err := u.passwords.Verify(hash, pass)
+	if err != nil || !ok || user == nil {
+		return false, err
+	}
+
+	if u.passwords.NeedsRehash(hash) {
+		// Failing to upgrade the hash doesn't fail the login, it is retried on the next one
+		if newHash, err := u.passwords.Hash(pass); err != nil {
+			logging.From(ctx).Error("failed to rehash password", zap.Error(err))
+		} else if err := u.store.SetPassword(ctx, *user.ID, hash, newHash); err != nil {
+			logging.From(ctx).Error("failed to rehash password", zap.Error(err))
+		}
+	}
+
+	return true, nil
+}
+
+// ValidatePassword checks a new password against the password policy.
+func (u *Users) ValidatePassword(pass string) error {
+	return u.passwords.Validate(pass)
+}
+
+func (u *Users) hashPassword(pass string) (string, error) {
+	if err := u.passwords.Validate(pass); err != nil {
+		return "", err
+	}
+	return u.passwords.Hash(pass)
+}
+
+// VerifyEmail will mark the email of the user as confirmed.
+func (u *Users) VerifyEmail(ctx context.Context, id string, email string) (*model.User, error) {
+	verifiedUser, err := u.store.SetVerified(ctx, id, email)
+	if err != nil {
+		return nil, err
+	}
+
+	u.events.Produce(ctx, events.TopicUsers, events.UserEvent{
+		EventType: events.EventTypeUserUpdated,
+		ID:        *verifiedUser.ID,
+		User:      verifiedUser,
+	})
+
+	return verifiedUser, nil
+}
+
+// SetRole will change what the user is allowed to do.
+func (u *Users) SetRole(ctx context.Context, id string, role model.Role) (*model.User, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole.Wrap(errors.ErrValidation)
+	}
+
+	updatedUser, err := u.store.SetRole(ctx, id, role)
+	if err != nil {
+		return nil, err
+	}
+
+	u.events.Produce(ctx, events.TopicUsers, events.UserEvent{
+		EventType: events.EventTypeUserUpdated,
+		ID:        *updatedUser.ID,
+		User:      updatedUser,
+	})
+
+	return updatedUser, nil
+}
+
+// PromoteAdmins will make the verified users with the emails admins. Unknown or unverified emails are skipped,
+// so an address can't be claimed as admin by registering it before its owner does.
+func (u *Users) PromoteAdmins(ctx context.Context, emails []string) error {
+	for _, email := range emails {
+		user, err := u.store.GetUserByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, errors.ErrNotFound) {
+				logging.From(ctx).Warn("admin account not found", zap.String("email", email))
+				continue
+			}
+			return err
+		}
+		if !user.IsVerified() {
+			logging.From(ctx).Warn("admin account is not verified", zap.String("email", email))
+			continue
+		}
+		if user.Role != nil && *user.Role == model.RoleAdmin {
+			continue
+		}
+
+		if _, err := u.SetRole(ctx, *user.ID, model.RoleAdmin); err != nil {
+			return err
+		}
+		logging.From(ctx).Info("promoted admin", zap.String("email", email))
+	}
+
+	return nil
+}
+
+// SetDisabled will disable the account of the user, or enable it again.
+func (u *Users) SetDisabled(ctx context.Context, id string, disabled bool) (*model.User, error) {
+	updatedUser, err := u.store.SetDisabled(ctx, id, disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	u.events.Produce(ctx, events.TopicUsers, events.UserEvent{
+		EventType: events.EventTypeUserUpdated,
+		ID:        *updatedUser.ID,
+		User:      updatedUser,
+	})
+
+	return updatedUser, nil
+}
+
+// SetAvatar will show the media record as the avatar of the user, nil removes the avatar.
+func (u *Users) SetAvatar(ctx context.Context, id string, avatarId *string) (*model.User, error) {
+	updatedUser, err := u.store.SetAvatar(ctx, id, avatarId)
+	if err != nil {
+		return nil, err
+	}
+
+	u.events.Produce(ctx, events.TopicUsers, events.UserEvent{
+		EventType: events.EventTypeUserUpdated,
+		ID:        *updatedUser.ID,
+		User:      updatedUser,
+	})
+
+	return updatedUser, nil
+}
+
+// GetUserByNickname will try to get an existing user with the nickname, ignoring the case.
+func (u *Users) GetUserByNickname(ctx context.Context, nickname string) (*model.User, error) {
+	return u.store.GetUserByNickname(ctx, nickname)
+}
+
 // GetUser will try to get an existing user in our database with the provided id.
 func (u *Users) GetUser(ctx context.Context, id string) (*model.User, error) {
 	user, err := u.store.GetUser(ctx, id)
@@ -137,7 +311,7 @@ func (u *Users) FindUsers(ctx context.Context, filters []model.Filter, offset, l
 		}
 
 		switch f.MatchType {
-		case model.MatchTypeEqual, model.MatchTypeLike:
+		case model.MatchTypeEqual, model.MatchTypeLike, model.MatchTypeSimilar:
 			// noop
 		default:
 			err := ErrInvalidFilterMatchType.Wrap(errors.ErrValidation)
@@ -146,7 +320,7 @@ func (u *Users) FindUsers(ctx context.Context, filters []model.Filter, offset, l
 		}
 
 		switch f.Field {
-		case model.FieldFirstName, model.FieldLastName, model.FieldNickname, model.FieldEmail:
+		case model.FieldFirstName, model.FieldLastName, model.FieldNickname, model.FieldEmail, model.FieldRole:
 		// noop
 		default:
 			err := ErrInvalidFilterField.Wrap(errors.ErrValidation)
@@ -163,6 +337,11 @@ func (u *Users) FindUsers(ctx context.Context, filters []model.Filter, offset, l
 	return users, nil
 }
 
+// ListUsers will retrieve all users using pagination if limit is gt 0.
+func (u *Users) ListUsers(ctx context.Context, offset, limit int64) ([]*model.User, error) {
+	return u.store.ListUsers(ctx, offset, limit)
+}
+
 // DeleteUser will try to delete an existing user in our database with the provided id.
 func (u *Users) DeleteUser(ctx context.Context, id string) error {
 	err := u.store.DeleteUser(ctx, id)
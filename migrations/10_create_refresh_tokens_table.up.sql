@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    id          uuid                     DEFAULT uuid_generate_v4(),
+    user_id     uuid                     NOT NULL,
+    family_id   uuid                     NOT NULL,
+    token_hash  VARCHAR(64)              NOT NULL,
+    replaced_by uuid                     DEFAULT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at  TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT refresh_tokens_token_hash_unique UNIQUE (token_hash),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+/* every refresh token issued for a single login shares the same family */
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS password_reset_tokens
+(
+    id         uuid                     DEFAULT uuid_generate_v4(),
+    user_id    uuid                     NOT NULL,
+    token_hash VARCHAR(64)              NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at    TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT password_reset_tokens_token_hash_unique UNIQUE (token_hash),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
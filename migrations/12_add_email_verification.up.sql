@@ -0,0 +1,23 @@
+alter table users
+    add verified_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+comment on column users.verified_at is 'When the user confirmed the email address, null until confirmed';
+
+/* accounts registered before verification existed have to confirm their email too, they may have been registered
+   with the email of someone else to read the quests sent to it */
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens
+(
+    id         uuid                     DEFAULT uuid_generate_v4(),
+    user_id    uuid                     NOT NULL,
+    email      VARCHAR(255)             NOT NULL,
+    token_hash VARCHAR(64)              NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at    TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT email_verification_tokens_token_hash_unique UNIQUE (token_hash),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens (user_id);
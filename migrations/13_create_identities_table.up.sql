@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    id            uuid                     DEFAULT uuid_generate_v4(),
+    user_id       uuid                     NOT NULL,
+    provider      VARCHAR(50)              NOT NULL,
+    subject       VARCHAR(255)             NOT NULL,
+    email         VARCHAR(255)             DEFAULT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_login_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT provider_subject_unique UNIQUE (provider, subject),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+/* login attempts started with an identity provider, waiting for the user to come back with the authorization code */
+CREATE TABLE IF NOT EXISTS oidc_login_states
+(
+    state_hash    VARCHAR(64)              NOT NULL,
+    provider      VARCHAR(50)              NOT NULL,
+    nonce         VARCHAR(255)             NOT NULL,
+    code_verifier VARCHAR(255)             NOT NULL,
+    expires_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (state_hash)
+);
@@ -0,0 +1,2 @@
+alter table media
+    drop column owner;
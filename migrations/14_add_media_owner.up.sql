@@ -0,0 +1,10 @@
+alter table media
+    add owner uuid DEFAULT NULL;
+
+comment on column media.owner is 'User who uploaded the file, null for files uploaded before owners were tracked';
+
+alter table media
+    add constraint media_owner_fk_users_id
+        foreign key (owner) references users (id) on delete set null;
+
+CREATE INDEX idx_media_owner ON media (owner);
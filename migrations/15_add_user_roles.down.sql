@@ -0,0 +1,5 @@
+alter table users
+    drop column disabled_at;
+
+alter table users
+    drop column role;
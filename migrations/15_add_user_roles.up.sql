@@ -0,0 +1,9 @@
+alter table users
+    add role VARCHAR(10) DEFAULT 'author' NOT NULL CHECK (role IN ('player', 'author', 'admin'));
+
+comment on column users.role is 'What the user is allowed to do: player, author or admin';
+
+alter table users
+    add disabled_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+comment on column users.disabled_at is 'When the account was disabled by an admin, null for active accounts';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_last_name_trgm;
+
+DROP INDEX IF EXISTS idx_users_first_name_trgm;
+
+DROP INDEX IF EXISTS idx_users_nickname_trgm;
@@ -0,0 +1,6 @@
+/* back the similarity search of users, pg_trgm is created in the first migration */
+CREATE INDEX idx_users_nickname_trgm ON users USING GIN (nickname gin_trgm_ops);
+
+CREATE INDEX idx_users_first_name_trgm ON users USING GIN (first_name gin_trgm_ops);
+
+CREATE INDEX idx_users_last_name_trgm ON users USING GIN (last_name gin_trgm_ops);
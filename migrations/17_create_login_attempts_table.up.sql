@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    id         uuid                     DEFAULT uuid_generate_v4(),
+    email      VARCHAR(255)             NOT NULL,
+    user_id    uuid                     DEFAULT NULL,
+    ip         VARCHAR(45)              NOT NULL,
+    success    BOOLEAN                  NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_login_attempts_email_created_at ON login_attempts (email, created_at);
+
+CREATE INDEX idx_login_attempts_ip_created_at ON login_attempts (ip, created_at);
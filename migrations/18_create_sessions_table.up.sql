@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id           uuid                     DEFAULT uuid_generate_v4(),
+    user_id      uuid                     NOT NULL,
+    user_agent   VARCHAR(512)             DEFAULT NULL,
+    ip           VARCHAR(45)              DEFAULT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+comment on table sessions is 'Devices signed in, the id is the family_id of the refresh tokens and the jti of the access tokens';
+
+/* sessions started before devices were tracked */
+INSERT INTO sessions(id, user_id, created_at, last_seen_at)
+SELECT family_id, user_id, min(created_at), max(created_at)
+FROM refresh_tokens
+GROUP BY family_id, user_id;
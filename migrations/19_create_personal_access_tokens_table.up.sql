@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens
+(
+    id           uuid                     DEFAULT uuid_generate_v4(),
+    user_id      uuid                     NOT NULL,
+    "name"       VARCHAR(100)             NOT NULL CHECK ("name" <> ''),
+    token_hash   VARCHAR(64)              NOT NULL,
+    scopes       VARCHAR(50)[]            NOT NULL,
+    expires_at   TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at   TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT personal_access_tokens_token_hash_unique UNIQUE (token_hash),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id        uuid                     NOT NULL,
+    secret         VARCHAR(64)              NOT NULL,
+    confirmed_at   TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    last_used_step BIGINT                   NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+comment on column user_totp.confirmed_at is 'Two-factor authentication is enabled only after the first code is confirmed';
+comment on column user_totp.last_used_step is 'Time step of the last accepted code, so codes can not be replayed';
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         uuid                     DEFAULT uuid_generate_v4(),
+    user_id    uuid                     NOT NULL,
+    code_hash  VARCHAR(64)              NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at    TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges
+(
+    id         uuid                     DEFAULT uuid_generate_v4(),
+    user_id    uuid                     NOT NULL,
+    token_hash VARCHAR(64)              NOT NULL,
+    attempts   INT                      NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at    TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT mfa_challenges_token_hash_unique UNIQUE (token_hash),
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+comment on table mfa_challenges is 'Logins which passed the password check and wait for the second factor';
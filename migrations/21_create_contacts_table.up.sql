@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS contacts
+(
+    id         uuid                     DEFAULT uuid_generate_v4(),
+    "owner"    uuid                     NOT NULL,
+    email      VARCHAR(255)             NOT NULL CHECK (email <> ''),
+    "name"     VARCHAR(255)             NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT contacts_owner_email_unique UNIQUE ("owner", email),
+    CONSTRAINT owner_fk_users_id FOREIGN KEY ("owner") REFERENCES users (id) ON DELETE CASCADE
+);
+
+/* back the autocomplete of contacts */
+CREATE INDEX idx_contacts_name_trgm ON contacts USING GIN ("name" gin_trgm_ops);
+
+CREATE INDEX idx_contacts_email_trgm ON contacts USING GIN (email gin_trgm_ops);
+
+/* fill the address books from the quests sent so far, the latest name given to an email wins */
+INSERT INTO contacts ("owner", email, "name", created_at, updated_at)
+SELECT DISTINCT ON (q.owner, qe.email) q.owner, qe.email, qe.name, now(), now()
+FROM quest_to_email qe
+         JOIN quests q ON q.id = qe.quest_id
+WHERE qe.email <> ''
+ORDER BY q.owner, qe.email, q.created_at DESC
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+alter table quests
+    drop column if exists published_at;
+
+DROP INDEX IF EXISTS users_nickname_unique;
+
+alter table users
+    drop column if exists avatar_id;
+
+alter table users
+    drop column if exists bio;
@@ -0,0 +1,24 @@
+alter table users
+    add bio VARCHAR(500) default '' not null;
+
+alter table users
+    add avatar_id uuid default null;
+
+alter table users
+    add constraint users_avatar_id_fk_media_id
+        foreign key (avatar_id) references media (id) on delete set null;
+
+/* nicknames were never unique, keep the oldest one and make the later ones unique with a part of the user id */
+UPDATE users u
+SET nickname = u.nickname || '-' || left(u.id::text, 8)
+FROM (SELECT id, row_number() OVER (PARTITION BY lower(nickname) ORDER BY created_at, id) AS n FROM users) d
+WHERE d.id = u.id
+  AND d.n > 1;
+
+/* public profiles are found by nickname, ignoring the case */
+CREATE UNIQUE INDEX users_nickname_unique ON users (lower(nickname));
+
+alter table quests
+    add published_at TIMESTAMP WITH TIME ZONE default null;
+
+comment on column quests.published_at is 'When the quest was published and shown on the profile of its owner, null for drafts';
@@ -0,0 +1,6 @@
+alter table steps
+    drop constraint steps_sort_unique;
+
+alter table steps
+    add constraint steps_pk
+        unique (quest_id, sort);
@@ -0,0 +1,7 @@
+/* reordering steps swaps their sort numbers, so the uniqueness is checked once the statement or the transaction is done */
+alter table steps
+    drop constraint steps_pk;
+
+alter table steps
+    add constraint steps_sort_unique
+        unique (quest_id, sort) deferrable initially immediate;
@@ -0,0 +1,4 @@
+alter table quest_to_email
+    drop column version_id;
+
+DROP TABLE IF EXISTS quest_versions;
@@ -0,0 +1,21 @@
+/* immutable snapshots of quests, every assignment plays the version it was sent with */
+CREATE TABLE IF NOT EXISTS quest_versions
+(
+    id           uuid                     DEFAULT uuid_generate_v4(),
+    quest_id     uuid                     NOT NULL,
+    version      int                      NOT NULL,
+    snapshot     jsonb                    NOT NULL,
+    content_hash VARCHAR(64)              NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT quest_versions_quest_version_unique UNIQUE (quest_id, version),
+    CONSTRAINT quest_id_fk_quests_id FOREIGN KEY (quest_id) REFERENCES quests (id) ON DELETE CASCADE
+);
+
+/* assignments sent before versions existed keep following the quest itself */
+alter table quest_to_email
+    add version_id uuid default null;
+
+alter table quest_to_email
+    add constraint version_id_fk_quest_versions_id
+        foreign key (version_id) references quest_versions (id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_quests_owner_state;
+
+alter table quests
+    drop column state;
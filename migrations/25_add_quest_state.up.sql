@@ -0,0 +1,12 @@
+/* lifecycle of a quest: only published quests can be sent, archived ones are kept for their recipients */
+alter table quests
+    add state VARCHAR(16) default 'draft' not null
+        constraint quests_state_check check (state IN ('draft', 'published', 'archived'));
+
+/* quests sent so far count as published */
+UPDATE quests
+SET state        = 'published',
+    published_at = COALESCE(published_at, updated_at)
+WHERE id IN (SELECT quest_id FROM quest_to_email);
+
+CREATE INDEX idx_quests_owner_state ON quests ("owner", state);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_steps_deleted_at;
+
+DROP INDEX IF EXISTS idx_quests_deleted_at;
+
+/* the trash is emptied, deleted steps would break the uniqueness */
+DELETE FROM steps WHERE deleted_at IS NOT NULL;
+
+alter table steps
+    drop constraint steps_sort_unique;
+
+alter table steps
+    add constraint steps_sort_unique
+        unique (quest_id, sort) deferrable initially immediate;
@@ -0,0 +1,11 @@
+/* deleted steps stay in the trash, so only steps which aren't deleted must have different sort */
+alter table steps
+    drop constraint steps_sort_unique;
+
+alter table steps
+    add constraint steps_sort_unique
+        exclude using btree (quest_id with =, sort with =) where (deleted_at IS NULL) deferrable initially immediate;
+
+CREATE INDEX idx_quests_deleted_at ON quests (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX idx_steps_deleted_at ON steps (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,15 @@
+/* curated quests authors can start from, their texts may contain {{variables}} */
+CREATE TABLE IF NOT EXISTS quest_templates
+(
+    id          uuid                     DEFAULT uuid_generate_v4(),
+    theme       VARCHAR(16)              NOT NULL,
+    "name"      VARCHAR(255)             NOT NULL CHECK ("name" <> ''),
+    description VARCHAR(255),
+    variables   jsonb                    NOT NULL DEFAULT '[]',
+    content     jsonb                    NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_quest_templates_theme ON quest_templates (theme);
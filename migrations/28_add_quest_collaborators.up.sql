@@ -0,0 +1,15 @@
+/* other users who co-author a quest, editors can change it and viewers can only see it */
+CREATE TABLE IF NOT EXISTS quest_collaborators
+(
+    quest_id   uuid                     NOT NULL,
+    user_id    uuid                     NOT NULL,
+    role       VARCHAR(16)              NOT NULL,
+    invited_by uuid                     NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (quest_id, user_id),
+    CONSTRAINT quest_collaborators_role_check CHECK (role IN ('editor', 'viewer')),
+    CONSTRAINT quest_id_fk_quests_id FOREIGN KEY (quest_id) REFERENCES quests (id) ON DELETE CASCADE,
+    CONSTRAINT user_id_fk_users_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_quest_collaborators_user_id ON quest_collaborators (user_id);
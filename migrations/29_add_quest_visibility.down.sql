@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_quests_visibility_published_at;
+
+alter table quests
+    drop column slug;
+
+alter table quests
+    drop column visibility;
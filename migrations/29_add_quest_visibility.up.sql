@@ -0,0 +1,11 @@
+/* public quests are listed in the catalogue and anyone signed in can enroll via their slug */
+alter table quests
+    add visibility VARCHAR(16) default 'private' not null
+        constraint quests_visibility_check check (visibility IN ('private', 'public'));
+
+/* the slug is kept when the quest is made private, so shared links work again once it is public */
+alter table quests
+    add slug VARCHAR(32) default null
+        constraint quests_slug_unique unique;
+
+CREATE INDEX idx_quests_visibility_published_at ON quests (visibility, published_at);
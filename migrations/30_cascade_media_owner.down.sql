@@ -0,0 +1,6 @@
+alter table media
+    drop constraint media_owner_fk_users_id;
+
+alter table media
+    add constraint media_owner_fk_users_id
+        foreign key (owner) references users (id) on delete set null;
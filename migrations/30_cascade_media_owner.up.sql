@@ -0,0 +1,7 @@
+/* media records are deleted together with their owner, the files are removed once the account is gone */
+alter table media
+    drop constraint media_owner_fk_users_id;
+
+alter table media
+    add constraint media_owner_fk_users_id
+        foreign key (owner) references users (id) on delete cascade;
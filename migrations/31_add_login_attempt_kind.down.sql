@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_login_attempts_user_id_kind_created_at;
+
+DELETE FROM login_attempts WHERE kind <> 'password';
+
+alter table login_attempts
+    drop column kind;
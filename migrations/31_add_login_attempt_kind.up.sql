@@ -0,0 +1,6 @@
+/* second factor codes are tracked next to passwords, so they are locked out per user across login challenges */
+alter table login_attempts
+    add kind VARCHAR(16) default 'password' not null
+        constraint login_attempts_kind_check check (kind IN ('password', 'mfa'));
+
+CREATE INDEX idx_login_attempts_user_id_kind_created_at ON login_attempts (user_id, kind, created_at);
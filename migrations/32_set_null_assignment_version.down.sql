@@ -0,0 +1,6 @@
+alter table quest_to_email
+    drop constraint version_id_fk_quest_versions_id;
+
+alter table quest_to_email
+    add constraint version_id_fk_quest_versions_id
+        foreign key (version_id) references quest_versions (id);
@@ -0,0 +1,7 @@
+/* assignments whose version is gone follow the quest itself, like the ones sent before versions existed */
+alter table quest_to_email
+    drop constraint version_id_fk_quest_versions_id;
+
+alter table quest_to_email
+    add constraint version_id_fk_quest_versions_id
+        foreign key (version_id) references quest_versions (id) on delete set null;
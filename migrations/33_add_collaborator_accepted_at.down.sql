@@ -0,0 +1,4 @@
+DELETE FROM quest_collaborators WHERE accepted_at IS NULL;
+
+alter table quest_collaborators
+    drop column accepted_at;
@@ -0,0 +1,7 @@
+/* sharing a quest invites the user, who gets access once they accept */
+alter table quest_collaborators
+    add accepted_at TIMESTAMP WITH TIME ZONE default null;
+
+/* collaborators invited so far already have access */
+UPDATE quest_collaborators
+SET accepted_at = created_at;
@@ -0,0 +1 @@
+/* accounts confirm their email again, there is nothing to restore */
@@ -0,0 +1,5 @@
+/* accounts registered before verification existed were marked verified when it was added, they have to confirm
+   their email like everyone else to keep playing the quests sent to it */
+UPDATE users
+SET verified_at = NULL
+WHERE verified_at = created_at;
@@ -0,0 +1 @@
+/* the snapshots are what the assignments played when they were pinned, there is nothing to restore */
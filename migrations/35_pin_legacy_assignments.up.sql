@@ -0,0 +1,39 @@
+/* assignments sent before versions existed followed the quest itself, they get a snapshot of the quest as it is now,
+   numbered after the versions the quest already has. The hash is not the one the app computes, so the next send saves
+   a new version even if nothing changed */
+INSERT INTO quest_versions (quest_id, version, snapshot, content_hash, created_at)
+SELECT v.quest_id, v.version, v.snapshot, encode(sha256(v.snapshot::text::bytea), 'hex'), now()
+FROM (SELECT q.id                                                                     AS quest_id,
+             (SELECT COALESCE(max(qv.version), 0) + 1 FROM quest_versions qv WHERE qv.quest_id = q.id) AS version,
+             jsonb_build_object(
+                     'id', q.id,
+                     'name', q.name,
+                     'description', q.description,
+                     'owner', q.owner,
+                     'theme', q.theme,
+                     'final_message', q.final_message,
+                     'rewards', q.rewards,
+                     'steps', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                                                                 'id', s.id,
+                                                                 'quest_id', s.quest_id,
+                                                                 'sort', s.sort,
+                                                                 'description', s.description,
+                                                                 'question_type', s.question_type,
+                                                                 'question_content', s.question_content,
+                                                                 'answer_type', s.answer_type,
+                                                                 'answer_content', s.answer_content
+                                                         ) ORDER BY s.sort)
+                                        FROM steps s
+                                        WHERE s.quest_id = q.id
+                                          AND s.deleted_at IS NULL), '[]'::jsonb)
+             )                                                                        AS snapshot
+      FROM quests q
+      WHERE EXISTS (SELECT 1 FROM quest_to_email qe WHERE qe.quest_id = q.id AND qe.version_id IS NULL)) v;
+
+UPDATE quest_to_email qe
+SET version_id = (SELECT qv.id
+                  FROM quest_versions qv
+                  WHERE qv.quest_id = qe.quest_id
+                  ORDER BY qv.version DESC
+                  LIMIT 1)
+WHERE qe.version_id IS NULL;